@@ -0,0 +1,476 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"shortener/config"
+	"shortener/internal/adapters/repository"
+	"shortener/internal/domain"
+	"shortener/internal/transfer"
+	"shortener/internal/usecase"
+	"shortener/pkg/logger"
+)
+
+const usage = `usage: app [command] [flags] [args]
+
+commands:
+  serve                 start the HTTP and gRPC servers (default)
+  migrate               manage the Postgres schema: up | down [-steps N] | status
+  create <url>          create a short link
+  resolve <code>        show the link behind a code
+  delete <code>         delete a link together with its clicks
+  export                write all links as JSON lines or CSV
+  import [file]         read links written by export from a file or stdin
+  canonicalize          rewrite stored URLs to their canonical form [-dry-run]
+
+create, resolve, delete, export, import and canonicalize accept -json for machine-readable output.
+
+The commands write to the storage directly, a running server with CACHE_ENABLED=true
+keeps serving its cached copy of a changed or deleted link until CACHE_TTL passes.`
+
+func run(args []string) int {
+	if len(args) == 0 {
+		serve()
+		return 0
+	}
+
+	// read-only commands must not change the storage, so they skip the
+	// compatibility check that records the generator settings and never
+	// migrate the schema
+	var cmd func(ctx context.Context, env *cliEnv, args []string) error
+	writes := true
+	switch args[0] {
+	case "serve":
+		serve()
+		return 0
+	case "migrate":
+		return migrateCommand(args[1:])
+	case "create":
+		cmd = createCommand
+	case "resolve":
+		cmd, writes = resolveCommand, false
+	case "delete":
+		cmd = deleteCommand
+	case "export":
+		cmd, writes = exportCommand, false
+	case "import":
+		cmd = importCommand
+	case "canonicalize":
+		cmd = canonicalizeCommand
+	case "help", "-h", "-help", "--help":
+		fmt.Println(usage)
+		return 0
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", args[0], usage)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	env, err := openCLI(ctx, writes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+	defer env.db.Close()
+
+	if err = cmd(ctx, env, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+
+		fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+		return 1
+	}
+
+	return 0
+}
+
+type cliEnv struct {
+	cfg config.Config
+	db  repository.Repository
+	uc  *usecase.Usecase
+	out io.Writer
+}
+
+func openCLI(ctx context.Context, writes bool) (*cliEnv, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if !writes {
+		cfg.Postgres.AutoMigrate = false
+	}
+
+	if cfg.Service.Storage == "memory" && cfg.Memory.DataDir == "" {
+		return nil, errors.New("memory storage without MEMORY_DATA_DIR lives only inside the server process")
+	}
+
+	log, err := logger.New(cfg.Service.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	warnDeprecated(cfg, log)
+
+	db, err := openRepository(ctx, cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	uc, err := newCLIUsecase(ctx, cfg, db, log, writes)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &cliEnv{
+		cfg: cfg,
+		db:  db,
+		uc:  uc,
+		out: os.Stdout,
+	}, nil
+}
+
+func newCLIUsecase(ctx context.Context, cfg config.Config, db repository.Repository, log logger.Logger, writes bool) (*usecase.Usecase, error) {
+	gen, err := newGenerator(cfg, db)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := newValidator(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if writes {
+		if err = checkCompat(ctx, cfg, db, v, log); err != nil {
+			return nil, err
+		}
+	}
+
+	p, err := newPolicy(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	var destinations usecase.Policy
+	if p != nil {
+		destinations = p
+	}
+
+	return usecase.NewUsecase(usecase.UsecaseOptions{
+		Repository:   db,
+		Generator:    gen,
+		Validator:    v,
+		Policy:       destinations,
+		MaxAttempts:  cfg.Service.MaxGenerateAttempts,
+		MaxBatchSize: cfg.Service.MaxBatchSize,
+		Protection:   cfg.Service.Protection,
+	})
+}
+
+// parseFlags allows flags both before and after the positional arguments.
+func parseFlags(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+
+		if flags.NArg() == 0 {
+			return positional, nil
+		}
+
+		positional = append(positional, flags.Arg(0))
+		args = flags.Args()[1:]
+	}
+}
+
+func (e *cliEnv) print(asJSON bool, v any, human string) error {
+	if asJSON {
+		return json.NewEncoder(e.out).Encode(v)
+	}
+
+	_, err := fmt.Fprintln(e.out, human)
+	return err
+}
+
+type linkOutput struct {
+	Shortened string     `json:"shortened"`
+	URL       string     `json:"url,omitempty"`
+	Original  string     `json:"original"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Protected bool       `json:"protected"`
+}
+
+func (e *cliEnv) linkOutput(link domain.Link) linkOutput {
+	out := linkOutput{
+		Shortened: link.Shortened,
+		Original:  link.Original,
+		ExpiresAt: link.ExpiresAt,
+		Protected: link.Protected(),
+	}
+
+	if !link.CreatedAt.IsZero() {
+		createdAt := link.CreatedAt.UTC()
+		out.CreatedAt = &createdAt
+	}
+
+	if e.cfg.Service.PublicURL != "" {
+		out.URL = e.cfg.Service.PublicURL + "/" + link.Shortened
+	}
+
+	return out
+}
+
+func createCommand(ctx context.Context, env *cliEnv, args []string) error {
+	flags := flag.NewFlagSet("create", flag.ContinueOnError)
+	alias := flags.String("alias", "", "custom code")
+	ttl := flags.Duration("ttl", 0, "link lifetime, e.g. 24h")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password required to open the link from stdin")
+	asJSON := flags.Bool("json", false, "print JSON")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: app create [-alias code] [-ttl duration] [-password-stdin] [-json] <url>")
+	}
+
+	// the password is not accepted as a flag value, which other users could
+	// read from the process list and the shell history
+	var password string
+	if *passwordStdin {
+		if password, err = readPassword(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	link, err := env.uc.CreateShortened(ctx, domain.CreateParams{
+		URL:      positional[0],
+		Alias:    *alias,
+		TTL:      *ttl,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+
+	out := env.linkOutput(link)
+	human := out.Shortened
+	if out.URL != "" {
+		human = out.URL
+	}
+
+	return env.print(*asJSON, out, human)
+}
+
+func resolveCommand(ctx context.Context, env *cliEnv, args []string) error {
+	flags := flag.NewFlagSet("resolve", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: app resolve [-json] <code>")
+	}
+
+	link, err := env.uc.GetLink(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	out := env.linkOutput(link)
+
+	human := fmt.Sprintf("shortened\t%s\noriginal\t%s", out.Shortened, out.Original)
+	if out.CreatedAt != nil {
+		human += "\ncreated_at\t" + out.CreatedAt.Format(time.RFC3339)
+	}
+	if out.ExpiresAt != nil {
+		human += "\nexpires_at\t" + out.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if out.Protected {
+		human += "\nprotected\ttrue"
+	}
+
+	return env.print(*asJSON, out, human)
+}
+
+func deleteCommand(ctx context.Context, env *cliEnv, args []string) error {
+	flags := flag.NewFlagSet("delete", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print JSON")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 1 {
+		return errors.New("usage: app delete [-json] <code>")
+	}
+
+	if err = env.uc.DeleteShortened(ctx, positional[0]); err != nil {
+		return err
+	}
+
+	return env.print(*asJSON, map[string]any{"shortened": positional[0], "deleted": true}, "deleted "+positional[0])
+}
+
+func exportCommand(ctx context.Context, env *cliEnv, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	output := flags.String("o", "", "output file (default stdout)")
+	format := flags.String("format", transfer.FormatJSONL, "jsonl or csv")
+	asJSON := flags.Bool("json", false, "print the summary as JSON")
+
+	if _, err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err := exportLinks(ctx, env, env.out, *format)
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	count, err := exportLinks(ctx, env, f, *format)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return env.print(*asJSON, map[string]int{"exported": count}, fmt.Sprintf("exported %d links", count))
+}
+
+// exportLinks writes a full backup with password hashes, the CLI already has
+// direct database access.
+func exportLinks(ctx context.Context, env *cliEnv, w io.Writer, format string) (int, error) {
+	return env.uc.ExportLinks(ctx, w, transfer.ExportOptions{
+		Format:         format,
+		PasswordHashes: true,
+	})
+}
+
+func importCommand(ctx context.Context, env *cliEnv, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := flags.String("format", transfer.FormatJSONL, "jsonl or csv")
+	conflict := flags.String("conflict", transfer.ConflictSkip, "existing code or url: skip, overwrite or fail")
+	asJSON := flags.Bool("json", false, "print the summary as JSON")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	switch len(positional) {
+	case 0:
+	case 1:
+		f, err := os.Open(positional[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		r = f
+	default:
+		return errors.New("usage: app import [-format jsonl|csv] [-conflict skip|overwrite|fail] [-json] [file]")
+	}
+
+	result, err := env.uc.ImportLinks(ctx, r, transfer.ImportOptions{
+		Format:   *format,
+		Conflict: *conflict,
+	})
+	summary := fmt.Sprintf("created %d, replaced %d, skipped %d, conflicts %d",
+		result.Created, result.Replaced, result.Skipped, len(result.Conflicts))
+	if err != nil {
+		return fmt.Errorf("%w (%s)", err, summary)
+	}
+
+	human := summary
+	for _, c := range result.Conflicts {
+		reason := "url taken by another code, code is new"
+		if c.Kind == transfer.KindNotReplaced {
+			reason = "url taken by another code, code kept its link"
+		}
+
+		human += fmt.Sprintf("\nline %d\t%s\t%s\t%s", c.Line, c.Shortened, c.Original, reason)
+	}
+
+	return env.print(*asJSON, result, human)
+}
+
+func canonicalizeCommand(ctx context.Context, env *cliEnv, args []string) error {
+	flags := flag.NewFlagSet("canonicalize", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count the links that would change")
+	asJSON := flags.Bool("json", false, "print the summary as JSON")
+
+	positional, err := parseFlags(flags, args)
+	if err != nil {
+		return err
+	}
+
+	if len(positional) != 0 {
+		return errors.New("usage: app canonicalize [-dry-run] [-json]")
+	}
+
+	result, err := env.uc.CanonicalizeLinks(ctx, *dryRun)
+	if err != nil {
+		return fmt.Errorf("%w (checked %d, updated %d)", err, result.Checked, result.Updated)
+	}
+
+	verb := "updated"
+	if *dryRun {
+		verb = "would update"
+	}
+
+	human := fmt.Sprintf("checked %d, %s %d", result.Checked, verb, result.Updated)
+	if len(result.Conflicts) > 0 {
+		human += fmt.Sprintf("\nconflicts (canonical URL taken by another link): %s", strings.Join(result.Conflicts, " "))
+	}
+	if len(result.Invalid) > 0 {
+		human += fmt.Sprintf("\ninvalid URLs left as is: %s", strings.Join(result.Invalid, " "))
+	}
+
+	return env.print(*asJSON, result, human)
+}
+
+func readPassword(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("empty password on stdin")
+	}
+
+	return password, nil
+}
@@ -3,22 +3,33 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"shortener/config"
-	"shortener/internal/adapters/repository"
+	"shortener/internal/adapters/repository/cache"
 	"shortener/internal/adapters/repository/memory"
 	"shortener/internal/adapters/repository/postgres"
+	"shortener/internal/analytics"
+	grpchandlers "shortener/internal/controllers/grpc"
 	httphandlers "shortener/internal/controllers/http_handlers"
-	"shortener/internal/generator"
+	"shortener/internal/controllers/http_handlers/middleware"
+	"shortener/internal/metrics"
+	"shortener/internal/ratelimit"
 	"shortener/internal/server"
+	"shortener/internal/sweeper"
+	"shortener/internal/tracing"
 	"shortener/internal/usecase"
-	"shortener/internal/validator"
 	"shortener/pkg/logger"
 	"syscall"
+	"time"
 )
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func serve() {
 	cfg, err := config.Load()
 	if err != nil {
 		panic(err)
@@ -31,26 +42,101 @@ func main() {
 
 	log.Info("service starts working")
 
+	warnDeprecated(cfg, log)
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
-	var db repository.Repository
-	if cfg.Service.InMemory {
-		db = memory.NewRepository()
-	} else {
-		db, err = postgres.NewRepository(ctx, cfg.Postgres)
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		ServiceName: cfg.Service.Name,
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		File:        cfg.Tracing.File,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		log.Error("tracing initialization error",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := shutdownTracing(shutdownCtx); err != nil {
+			log.Error("tracing shutdown error",
+				logger.Field{Key: "error", Value: err})
+		}
+	}()
+
+	var prom *metrics.Metrics
+	if cfg.Service.Metrics {
+		prom = metrics.New()
+	}
+
+	db, err := openRepository(ctx, cfg, log)
+	if err != nil {
+		log.Error("database initialization error",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
+	defer db.Close()
+
+	switch typed := db.(type) {
+	case *memory.MemoryRepository:
+		if cfg.Memory.DataDir != "" && cfg.Memory.SnapshotInterval > 0 {
+			go typed.RunSnapshots(ctx, cfg.Memory.SnapshotInterval, log)
+		}
+	case *postgres.PostgresRepository:
+		if prom != nil {
+			if err = prom.Register(metrics.NewPoolCollector(typed.Stat)); err != nil {
+				log.Error("metrics initialization error",
+					logger.Field{Key: "error", Value: err})
+
+				return
+			}
+		}
+	}
+
+	var ucRepo usecase.Repository = db
+	if cfg.Cache.Enabled {
+		var cacheMetrics cache.Metrics
+		if prom != nil {
+			cacheMetrics = prom
+		}
+
+		cached, err := cache.NewRepository(db, cache.Options{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+			Metrics:     cacheMetrics,
+		})
 		if err != nil {
-			log.Error("database initialization error",
+			log.Error("cache initialization error",
 				logger.Field{Key: "error", Value: err})
 
 			return
 		}
-		defer db.Close()
+
+		ucRepo = cached
 	}
 
-	generator := generator.NewGenerator(cfg.Generator.Alphabet, cfg.Generator.Len)
+	if cfg.Service.SweepInterval > 0 {
+		go sweeper.NewSweeper(ucRepo, cfg.Service.SweepInterval, log).Run(ctx)
+	}
+
+	gen, err := newGenerator(cfg, db)
+	if err != nil {
+		log.Error("generator initialization error",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
 
-	validator, err := validator.NewValidator(cfg.Generator.Alphabet, cfg.Generator.Len)
+	validator, err := newValidator(cfg)
 	if err != nil {
 		log.Error("validator initialization error",
 			logger.Field{Key: "error", Value: err})
@@ -58,12 +144,69 @@ func main() {
 		return
 	}
 
+	if err = checkCompat(ctx, cfg, db, validator, log); err != nil {
+		log.Error("storage is incompatible with generator settings",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
+
+	p, err := newPolicy(cfg, log)
+	if err != nil {
+		log.Error("policy initialization error",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
+
+	var destinations usecase.Policy
+	if p != nil {
+		go p.Run(ctx)
+
+		destinations = p
+	}
+
+	clicksDone := make(chan struct{})
+
+	var clicks usecase.ClickRecorder
+	var recorder *analytics.Recorder
+	if cfg.Analytics.Enabled {
+		recorder = analytics.NewRecorder(db, log, analytics.Options{
+			BatchSize:     cfg.Analytics.BatchSize,
+			QueueSize:     cfg.Analytics.QueueSize,
+			FlushInterval: cfg.Analytics.FlushInterval,
+		})
+
+		go func() {
+			defer close(clicksDone)
+			recorder.Run()
+		}()
+
+		clicks = recorder
+	} else {
+		close(clicksDone)
+	}
+
+	var ucMetrics usecase.Metrics
+	var observer middleware.RequestObserver
+	var metricsHandler http.Handler
+	if prom != nil {
+		ucMetrics = prom
+		observer = prom
+		metricsHandler = prom.Handler()
+	}
+
 	uc, err := usecase.NewUsecase(usecase.UsecaseOptions{
-		Repository:  db,
-		Generator:   generator,
-		Validator:   validator,
-		MaxAttempts: cfg.Service.MaxGenerateAttempts,
-		Protection:  cfg.Service.Protection,
+		Repository:   ucRepo,
+		Generator:    gen,
+		Validator:    validator,
+		Clicks:       clicks,
+		Metrics:      ucMetrics,
+		Policy:       destinations,
+		Lockout:      ratelimit.NewLockout(cfg.Service.PasswordMaxAttempts, cfg.Service.PasswordLockout),
+		MaxAttempts:  cfg.Service.MaxGenerateAttempts,
+		MaxBatchSize: cfg.Service.MaxBatchSize,
+		Protection:   cfg.Service.Protection,
 	})
 	if err != nil {
 		log.Error("usecase initialization error",
@@ -72,9 +215,74 @@ func main() {
 		return
 	}
 
-	apiControllers := httphandlers.NewHandlers(uc)
+	apiControllers, err := httphandlers.NewHandlers(uc, cfg.Service.RedirectStatus, cfg.Service.PublicURL)
+	if err != nil {
+		log.Error("handlers initialization error",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
+
+	// limiters are shared by HTTP and gRPC so that a client cannot double its
+	// quota by switching transports
+	var createLimiter, resolveLimiter *ratelimit.Limiter
+	if cfg.Service.CreateRateLimit > 0 {
+		createLimiter = ratelimit.NewLimiter(cfg.Service.CreateRateLimit, cfg.Service.CreateRateBurst)
+	}
+
+	if cfg.Service.ResolveRateLimit > 0 {
+		resolveLimiter = ratelimit.NewLimiter(cfg.Service.ResolveRateLimit, cfg.Service.ResolveRateBurst)
+	}
+
+	mw := middleware.NewMiddleware(log, middleware.Options{
+		Keys:           db,
+		Observer:       observer,
+		AuthEnabled:    cfg.Auth.Enabled,
+		CreateLimiter:  createLimiter,
+		ResolveLimiter: resolveLimiter,
+	})
+
+	srv, err := server.NewServer(apiControllers, mw, metricsHandler, server.Options{
+		TransferTimeout: cfg.Service.TransferTimeout,
+		ProxyHeader:     cfg.Service.ProxyHeader,
+		TrustedProxies:  cfg.Service.TrustedProxies,
+	}, log)
+	if err != nil {
+		log.Error("server initialization error",
+			logger.Field{Key: "error", Value: err})
+
+		return
+	}
+
+	grpcDone := make(chan struct{})
 
-	srv := server.NewServer(apiControllers, log)
+	if cfg.Service.GRPCPort > 0 {
+		interceptors := grpchandlers.NewInterceptors(log, grpchandlers.InterceptorOptions{
+			Keys:           db,
+			AuthEnabled:    cfg.Auth.Enabled,
+			CreateLimiter:  createLimiter,
+			ResolveLimiter: resolveLimiter,
+		})
+
+		grpcSrv := server.NewGRPCServer(grpchandlers.NewHandlers(uc, log), interceptors, log)
+
+		go func() {
+			defer close(grpcDone)
+
+			if err := grpcSrv.Run(ctx, fmt.Sprintf("%s:%d", cfg.Service.Host, cfg.Service.GRPCPort)); err != nil {
+				log.Error("grpc server died",
+					logger.Field{Key: "error", Value: err})
+
+				stop()
+			}
+		}()
+	} else {
+		close(grpcDone)
+	}
+
+	if !cfg.Auth.Enabled {
+		log.Warn("AUTH_ENABLED is false: anyone who can reach the service may create, change and delete links")
+	}
 
 	if err = srv.Run(ctx, fmt.Sprintf("%s:%d", cfg.Service.Host, cfg.Service.Port)); err != nil {
 		log.Error("server died",
@@ -83,5 +291,13 @@ func main() {
 		return
 	}
 
+	<-grpcDone
+
+	// both servers are done with their requests, so no click is lost
+	if recorder != nil {
+		recorder.Close()
+	}
+	<-clicksDone
+
 	log.Info("service successfully stopped")
 }
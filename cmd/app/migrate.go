@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"shortener/config"
+	"shortener/internal/adapters/repository/postgres"
+)
+
+const migrateUsage = "usage: app migrate up | down [-steps N] | status"
+
+func migrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	if err := runMigrate(args[0], args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "migrate:", err)
+		return 1
+	}
+
+	return 0
+}
+
+func runMigrate(action string, args []string) error {
+	flags := flag.NewFlagSet("migrate "+action, flag.ContinueOnError)
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	if cfg.Service.Storage != "postgres" {
+		return fmt.Errorf("storage %q manages its schema itself", cfg.Service.Storage)
+	}
+
+	ctx := context.Background()
+
+	cfg.Postgres.AutoMigrate = false
+	db, err := postgres.NewRepository(ctx, cfg.Postgres)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	switch action {
+	case "up":
+		return db.MigrateUp(ctx)
+	case "down":
+		if *steps <= 0 {
+			return errors.New("steps must be positive")
+		}
+
+		return db.MigrateDown(ctx, *steps)
+	case "status":
+		statuses, err := db.MigrationStatus(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+
+			fmt.Printf("%03d_%s\t%s\n", s.Version, s.Name, state)
+		}
+
+		return nil
+	default:
+		return errors.New(migrateUsage)
+	}
+}
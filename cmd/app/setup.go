@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"shortener/config"
+	"shortener/internal/adapters/repository"
+	"shortener/internal/adapters/repository/memory"
+	"shortener/internal/adapters/repository/postgres"
+	"shortener/internal/adapters/repository/sqlite"
+	"shortener/internal/compat"
+	"shortener/internal/generator"
+	"shortener/internal/policy"
+	"shortener/internal/usecase"
+	"shortener/internal/validator"
+	"shortener/pkg/logger"
+)
+
+func openRepository(ctx context.Context, cfg config.Config, log logger.Logger) (repository.Repository, error) {
+	switch cfg.Service.Storage {
+	case "memory":
+		memDB := memory.NewRepository()
+		if cfg.Memory.DataDir != "" {
+			var err error
+			if memDB, err = memory.NewDurableRepository(cfg.Memory.DataDir, log); err != nil {
+				return nil, fmt.Errorf("memory storage recovery: %w", err)
+			}
+		}
+
+		if cfg.Auth.KeysFile != "" {
+			if err := memDB.LoadAPIKeys(cfg.Auth.KeysFile); err != nil {
+				memDB.Close()
+				return nil, fmt.Errorf("api keys loading: %w", err)
+			}
+		}
+
+		return memDB, nil
+	case "postgres":
+		return postgres.NewRepository(ctx, cfg.Postgres)
+	case "sqlite":
+		return sqlite.NewRepository(ctx, cfg.Sqlite)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", cfg.Service.Storage)
+	}
+}
+
+func newGenerator(cfg config.Config, ids generator.IDSource) (usecase.Generator, error) {
+	switch cfg.Generator.Strategy {
+	case "random":
+		return generator.NewGenerator(cfg.Generator.Alphabet, cfg.Generator.Len), nil
+	case "counter":
+		return generator.NewCounterGenerator(ids, cfg.Generator.Alphabet, cfg.Generator.Len, cfg.Generator.ScrambleKey)
+	case "hash":
+		return generator.NewHashGenerator(cfg.Generator.Secret, cfg.Generator.Alphabet, cfg.Generator.Len)
+	default:
+		return nil, fmt.Errorf("unknown generator strategy %q", cfg.Generator.Strategy)
+	}
+}
+
+func newValidator(cfg config.Config) (*validator.Validator, error) {
+	v, err := validator.NewValidator(cfg.Generator.Alphabet, cfg.Generator.Len)
+	if err != nil {
+		return nil, err
+	}
+
+	v.SetCanonicalizer(validator.NewCanonicalizer(validator.CanonicalizerOptions{
+		StripTracking:  cfg.URL.StripTracking,
+		TrackingParams: cfg.URL.TrackingParams,
+		DropFragment:   cfg.URL.DropFragment,
+	}))
+
+	return v, nil
+}
+
+func checkCompat(ctx context.Context, cfg config.Config, db repository.Repository, v *validator.Validator, log logger.Logger) error {
+	return compat.Check(ctx, db, v, compat.Options{
+		Alphabet:    cfg.Generator.Alphabet,
+		Len:         cfg.Generator.Len,
+		AutoMigrate: cfg.Postgres.AutoMigrate,
+		Protection:  cfg.Service.Protection,
+	}, log)
+}
+
+func warnDeprecated(cfg config.Config, log logger.Logger) {
+	if cfg.Service.InMemoryMode {
+		log.Warn("SERVICE_IN_MEMORY_MODE is deprecated, set SERVICE_STORAGE=memory instead")
+	}
+}
+
+// newPolicy returns nil when SERVICE_PROTECTION is off. Otherwise internal and
+// self-referencing destinations are always refused and POLICY_FILE only adds
+// allow/deny rules on top.
+func newPolicy(cfg config.Config, log logger.Logger) (*policy.Policy, error) {
+	if !cfg.Service.Protection {
+		return nil, nil
+	}
+
+	return policy.NewPolicy(policy.Options{
+		File:           cfg.Policy.File,
+		ReloadInterval: cfg.Policy.ReloadInterval,
+		SelfHosts:      selfHosts(cfg),
+	}, log)
+}
+
+// selfHosts adds the service's own public and listen hosts to
+// POLICY_SELF_HOSTS so that links back to the shortener are refused even when
+// the list is not maintained by hand.
+func selfHosts(cfg config.Config) []string {
+	hosts := append([]string{}, cfg.Policy.SelfHosts...)
+
+	if cfg.Service.PublicURL != "" {
+		if parsed, err := url.Parse(cfg.Service.PublicURL); err == nil && parsed.Hostname() != "" {
+			hosts = append(hosts, parsed.Hostname())
+		}
+	}
+
+	if cfg.Service.Host != "" {
+		hosts = append(hosts, cfg.Service.Host)
+	}
+
+	return hosts
+}
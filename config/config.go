@@ -1,36 +1,121 @@
 package config
 
-import "github.com/ilyakaznacheev/cleanenv"
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ilyakaznacheev/cleanenv"
+)
 
 type Service struct {
-	Name                string `env:"NAME" env-default:"shortener"`
-	Host                string `env:"HOST" env-required:"true"`
-	Port                int    `env:"PORT" env-required:"true"`
-	MaxGenerateAttempts int    `env:"MAX_GENERATE_ATTEMPTS" env-default:"3"`
-	InMemory            bool   `env:"IN_MEMORY_MODE" env-default:"false"`
-	Protection          bool   `env:"PROTECTION" env-default:"true"`
+	Name                string        `env:"NAME" env-default:"shortener"`
+	Host                string        `env:"HOST" env-required:"true"`
+	Port                int           `env:"PORT" env-required:"true"`
+	GRPCPort            int           `env:"GRPC_PORT" env-default:"0"`
+	MaxGenerateAttempts int           `env:"MAX_GENERATE_ATTEMPTS" env-default:"3"`
+	MaxBatchSize        int           `env:"MAX_BATCH_SIZE" env-default:"1000"`
+	Storage             string        `env:"STORAGE" env-default:"postgres"`
+	Protection          bool          `env:"PROTECTION" env-default:"true"`
+	Metrics             bool          `env:"METRICS" env-default:"true"`
+	RedirectStatus      int           `env:"REDIRECT_STATUS" env-default:"302"`
+	PublicURL           string        `env:"PUBLIC_URL"`
+	SweepInterval       time.Duration `env:"SWEEP_INTERVAL" env-default:"1m"`
+	CreateRateLimit     float64       `env:"CREATE_RATE_LIMIT" env-default:"0"`
+	CreateRateBurst     int           `env:"CREATE_RATE_BURST" env-default:"10"`
+	ResolveRateLimit    float64       `env:"RESOLVE_RATE_LIMIT" env-default:"0"`
+	ResolveRateBurst    int           `env:"RESOLVE_RATE_BURST" env-default:"50"`
+	PasswordMaxAttempts int           `env:"PASSWORD_MAX_ATTEMPTS" env-default:"5"`
+	PasswordLockout     time.Duration `env:"PASSWORD_LOCKOUT" env-default:"15m"`
+	TransferTimeout     time.Duration `env:"TRANSFER_TIMEOUT" env-default:"1h"`
+	ProxyHeader         string        `env:"PROXY_HEADER"`
+	TrustedProxies      []string      `env:"TRUSTED_PROXIES" env-separator:","`
+
+	// Deprecated: InMemoryMode is mapped to Storage "memory" by Load.
+	InMemoryMode bool `env:"IN_MEMORY_MODE" env-default:"false"`
 }
 
 type Postgres struct {
-	Host     string `env:"HOST"`
-	User     string `env:"USER"`
-	Password string `env:"PASSWORD"`
-	Name     string `env:"NAME"`
-	SSLMode  string `env:"SSL_MODE"`
-	Port     int    `env:"PORT"`
-	MaxConns int    `env:"MAX_CONNS" env-default:"20"`
-	MinConns int    `env:"MIN_CONNS" env-default:"2"`
+	Host        string `env:"HOST"`
+	User        string `env:"USER"`
+	Password    string `env:"PASSWORD"`
+	Name        string `env:"NAME"`
+	SSLMode     string `env:"SSL_MODE"`
+	Port        int    `env:"PORT"`
+	MaxConns    int    `env:"MAX_CONNS" env-default:"20"`
+	MinConns    int    `env:"MIN_CONNS" env-default:"2"`
+	AutoMigrate bool   `env:"AUTO_MIGRATE" env-default:"false"`
+}
+
+type Sqlite struct {
+	Path        string        `env:"PATH" env-default:"shortener.db"`
+	BusyTimeout time.Duration `env:"BUSY_TIMEOUT" env-default:"5s"`
+}
+
+type Memory struct {
+	DataDir          string        `env:"DATA_DIR"`
+	SnapshotInterval time.Duration `env:"SNAPSHOT_INTERVAL" env-default:"5m"`
 }
 
 type Generator struct {
-	Alphabet string `env:"ALPHABET" env-required:"true"`
-	Len      int    `env:"LEN" env-required:"true"`
+	Alphabet    string `env:"ALPHABET" env-required:"true"`
+	Len         int    `env:"LEN" env-required:"true"`
+	Strategy    string `env:"STRATEGY" env-default:"random"`
+	ScrambleKey string `env:"SCRAMBLE_KEY"`
+	Secret      string `env:"SECRET"`
+}
+
+type Analytics struct {
+	Enabled       bool          `env:"ENABLED" env-default:"true"`
+	BatchSize     int           `env:"BATCH_SIZE" env-default:"500"`
+	QueueSize     int           `env:"QUEUE_SIZE" env-default:"10000"`
+	FlushInterval time.Duration `env:"FLUSH_INTERVAL" env-default:"1s"`
+}
+
+type Auth struct {
+	Enabled  bool   `env:"ENABLED" env-default:"false"`
+	KeysFile string `env:"KEYS_FILE"`
+}
+
+type Cache struct {
+	Enabled     bool          `env:"ENABLED" env-default:"false"`
+	Size        int           `env:"SIZE" env-default:"10000"`
+	TTL         time.Duration `env:"TTL" env-default:"5m"`
+	NegativeTTL time.Duration `env:"NEGATIVE_TTL" env-default:"10s"`
+}
+
+type Tracing struct {
+	Exporter    string  `env:"EXPORTER" env-default:"none"`
+	Endpoint    string  `env:"ENDPOINT"`
+	Insecure    bool    `env:"INSECURE" env-default:"false"`
+	File        string  `env:"FILE" env-default:"traces.jsonl"`
+	SampleRatio float64 `env:"SAMPLE_RATIO" env-default:"1"`
+}
+
+type URL struct {
+	StripTracking  bool     `env:"STRIP_TRACKING" env-default:"true"`
+	TrackingParams []string `env:"TRACKING_PARAMS" env-separator:","`
+	DropFragment   bool     `env:"DROP_FRAGMENT" env-default:"false"`
+}
+
+type Policy struct {
+	File           string        `env:"FILE"`
+	ReloadInterval time.Duration `env:"RELOAD_INTERVAL" env-default:"10s"`
+	SelfHosts      []string      `env:"SELF_HOSTS" env-separator:","`
 }
 
 type Config struct {
 	Postgres  Postgres  `env-prefix:"DB_"`
+	Sqlite    Sqlite    `env-prefix:"SQLITE_"`
+	Memory    Memory    `env-prefix:"MEMORY_"`
 	Service   Service   `env-prefix:"SERVICE_"`
 	Generator Generator `env-prefix:"GENERATOR_"`
+	Analytics Analytics `env-prefix:"ANALYTICS_"`
+	Auth      Auth      `env-prefix:"AUTH_"`
+	Tracing   Tracing   `env-prefix:"TRACING_"`
+	Cache     Cache     `env-prefix:"CACHE_"`
+	URL       URL       `env-prefix:"URL_"`
+	Policy    Policy    `env-prefix:"POLICY_"`
 }
 
 func Load() (Config, error) {
@@ -40,5 +125,15 @@ func Load() (Config, error) {
 		return Config{}, err
 	}
 
+	// deployments predating SERVICE_STORAGE would otherwise silently
+	// switch to postgres
+	if cfg.Service.InMemoryMode {
+		if storage, ok := os.LookupEnv("SERVICE_STORAGE"); ok && storage != "memory" {
+			return Config{}, fmt.Errorf("SERVICE_IN_MEMORY_MODE=true conflicts with SERVICE_STORAGE=%s, remove the deprecated SERVICE_IN_MEMORY_MODE", storage)
+		}
+
+		cfg.Service.Storage = "memory"
+	}
+
 	return cfg, nil
 }
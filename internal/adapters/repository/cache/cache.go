@@ -0,0 +1,198 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/internal/usecase"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type Metrics interface {
+	CacheHit()
+	CacheMiss()
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) CacheHit()  {}
+func (nopMetrics) CacheMiss() {}
+
+type Options struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	Metrics     Metrics
+}
+
+type CachedRepository struct {
+	usecase.Repository
+
+	mu          sync.Mutex
+	items       *lru
+	generation  uint64
+	group       singleflight.Group
+	ttl         time.Duration
+	negativeTTL time.Duration
+	metrics     Metrics
+}
+
+func NewRepository(repo usecase.Repository, options Options) (*CachedRepository, error) {
+	if options.Size <= 0 {
+		return nil, errors.New("cache size must be positive")
+	}
+
+	if options.TTL <= 0 {
+		return nil, errors.New("cache ttl must be positive")
+	}
+
+	if options.Metrics == nil {
+		options.Metrics = nopMetrics{}
+	}
+
+	return &CachedRepository{
+		Repository:  repo,
+		items:       newLRU(options.Size),
+		ttl:         options.TTL,
+		negativeTTL: options.NegativeTTL,
+		metrics:     options.Metrics,
+	}, nil
+}
+
+type loadResult struct {
+	link     domain.Link
+	notFound bool
+}
+
+func (r *CachedRepository) GetByShortened(ctx context.Context, shortened string) (domain.Link, error) {
+	r.mu.Lock()
+	e, ok := r.items.get(shortened, time.Now())
+	generation := r.generation
+	r.mu.Unlock()
+
+	if ok {
+		r.metrics.CacheHit()
+		if e.notFound {
+			return domain.Link{}, domain.ErrNotFound
+		}
+
+		return e.link, nil
+	}
+
+	r.metrics.CacheMiss()
+
+	v, err, _ := r.group.Do(shortened, func() (any, error) {
+		link, err := r.Repository.GetByShortened(context.WithoutCancel(ctx), shortened)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+
+		res := loadResult{link: link, notFound: err != nil}
+		r.store(shortened, res, generation)
+
+		return res, nil
+	})
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	res := v.(loadResult)
+	if res.notFound {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	return res.link, nil
+}
+
+func (r *CachedRepository) Save(ctx context.Context, link domain.Link) error {
+	defer r.invalidate(link.Shortened)
+
+	return r.Repository.Save(ctx, link)
+}
+
+func (r *CachedRepository) SaveBatch(ctx context.Context, links []domain.Link) ([]error, error) {
+	keys := make([]string, 0, len(links))
+	for _, link := range links {
+		keys = append(keys, link.Shortened)
+	}
+	defer r.invalidate(keys...)
+
+	return r.Repository.SaveBatch(ctx, links)
+}
+
+func (r *CachedRepository) Delete(ctx context.Context, shortened string) error {
+	defer r.invalidate(shortened)
+
+	return r.Repository.Delete(ctx, shortened)
+}
+
+func (r *CachedRepository) UpdateOriginal(ctx context.Context, shortened, original string) error {
+	defer r.invalidate(shortened)
+
+	return r.Repository.UpdateOriginal(ctx, shortened, original)
+}
+
+func (r *CachedRepository) Replace(ctx context.Context, link domain.Link) error {
+	defer r.invalidate(link.Shortened)
+
+	return r.Repository.Replace(ctx, link)
+}
+
+func (r *CachedRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	n, err := r.Repository.DeleteExpired(ctx, before)
+
+	r.mu.Lock()
+	r.generation++
+	r.items.removeFunc(func(e entry) bool {
+		return e.link.ExpiresAt != nil && !before.Before(*e.link.ExpiresAt)
+	})
+	r.mu.Unlock()
+
+	return n, err
+}
+
+func (r *CachedRepository) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.items.len()
+}
+
+func (r *CachedRepository) store(shortened string, res loadResult, generation uint64) {
+	ttl := r.ttl
+	if res.notFound {
+		ttl = r.negativeTTL
+	}
+
+	if ttl <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if generation != r.generation {
+		return
+	}
+
+	r.items.add(entry{
+		key:       shortened,
+		link:      res.link,
+		notFound:  res.notFound,
+		expiresAt: time.Now().Add(ttl),
+	})
+}
+
+func (r *CachedRepository) invalidate(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.generation++
+	for _, key := range keys {
+		r.items.remove(key)
+	}
+}
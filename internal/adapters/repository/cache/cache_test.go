@@ -0,0 +1,193 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"shortener/internal/adapters/repository/cache"
+	"shortener/internal/adapters/repository/memory"
+	"shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type countingRepository struct {
+	*memory.MemoryRepository
+
+	lookups atomic.Int64
+	delay   time.Duration
+}
+
+func (r *countingRepository) GetByShortened(ctx context.Context, shortened string) (domain.Link, error) {
+	r.lookups.Add(1)
+	time.Sleep(r.delay)
+
+	return r.MemoryRepository.GetByShortened(ctx, shortened)
+}
+
+type counters struct {
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func (c *counters) CacheHit()  { c.hits.Add(1) }
+func (c *counters) CacheMiss() { c.misses.Add(1) }
+
+func newCache(t *testing.T, options cache.Options) (*cache.CachedRepository, *countingRepository) {
+	t.Helper()
+
+	repo := &countingRepository{MemoryRepository: memory.NewRepository()}
+
+	cached, err := cache.NewRepository(repo, options)
+	require.NoError(t, err)
+
+	return cached, repo
+}
+
+func TestGetByShortened(t *testing.T) {
+	ctx := context.Background()
+	metrics := &counters{}
+	cached, repo := newCache(t, cache.Options{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute, Metrics: metrics})
+
+	require.NoError(t, repo.Save(ctx, domain.Link{Original: "example", Shortened: "ok"}))
+
+	for range 3 {
+		link, err := cached.GetByShortened(ctx, "ok")
+		require.NoError(t, err)
+		assert.Equal(t, "example", link.Original)
+	}
+
+	for range 2 {
+		_, err := cached.GetByShortened(ctx, "missing")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	}
+
+	assert.EqualValues(t, 2, repo.lookups.Load())
+	assert.EqualValues(t, 3, metrics.hits.Load())
+	assert.EqualValues(t, 2, metrics.misses.Load())
+}
+
+func TestInvalidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		mutate func(r *cache.CachedRepository) error
+		want   string
+	}{
+		{
+			name: "update",
+			mutate: func(r *cache.CachedRepository) error {
+				return r.UpdateOriginal(ctx, "ok", "updated")
+			},
+			want: "updated",
+		},
+		{
+			name: "delete",
+			mutate: func(r *cache.CachedRepository) error {
+				return r.Delete(ctx, "ok")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cached, _ := newCache(t, cache.Options{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+
+			require.NoError(t, cached.Save(ctx, domain.Link{Original: "example", Shortened: "ok"}))
+
+			_, err := cached.GetByShortened(ctx, "ok")
+			require.NoError(t, err)
+
+			require.NoError(t, tt.mutate(cached))
+
+			link, err := cached.GetByShortened(ctx, "ok")
+			if tt.want == "" {
+				assert.ErrorIs(t, err, domain.ErrNotFound)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, link.Original)
+		})
+	}
+}
+
+func TestSaveDropsNegativeEntry(t *testing.T) {
+	ctx := context.Background()
+	cached, _ := newCache(t, cache.Options{Size: 10, TTL: time.Minute, NegativeTTL: time.Minute})
+
+	_, err := cached.GetByShortened(ctx, "ok")
+	require.ErrorIs(t, err, domain.ErrNotFound)
+
+	require.NoError(t, cached.Save(ctx, domain.Link{Original: "example", Shortened: "ok"}))
+
+	link, err := cached.GetByShortened(ctx, "ok")
+	require.NoError(t, err)
+	assert.Equal(t, "example", link.Original)
+}
+
+func TestEviction(t *testing.T) {
+	ctx := context.Background()
+	cached, repo := newCache(t, cache.Options{Size: 2, TTL: time.Minute})
+
+	for _, code := range []string{"a", "b", "c"} {
+		require.NoError(t, repo.Save(ctx, domain.Link{Original: "example-" + code, Shortened: code}))
+
+		_, err := cached.GetByShortened(ctx, code)
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, cached.Len())
+
+	_, err := cached.GetByShortened(ctx, "a")
+	require.NoError(t, err)
+	assert.EqualValues(t, 4, repo.lookups.Load())
+}
+
+func TestTTL(t *testing.T) {
+	ctx := context.Background()
+	cached, repo := newCache(t, cache.Options{Size: 10, TTL: 20 * time.Millisecond})
+
+	require.NoError(t, repo.Save(ctx, domain.Link{Original: "example", Shortened: "ok"}))
+
+	_, err := cached.GetByShortened(ctx, "ok")
+	require.NoError(t, err)
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, err = cached.GetByShortened(ctx, "ok")
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, repo.lookups.Load())
+}
+
+func TestSingleflight(t *testing.T) {
+	ctx := context.Background()
+	cached, repo := newCache(t, cache.Options{Size: 10, TTL: time.Minute})
+	repo.delay = 50 * time.Millisecond
+
+	require.NoError(t, repo.Save(ctx, domain.Link{Original: "example", Shortened: "ok"}))
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			_, err := cached.GetByShortened(ctx, "ok")
+			assert.NoError(t, err)
+		})
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, repo.lookups.Load())
+}
+
+func TestNewRepository(t *testing.T) {
+	_, err := cache.NewRepository(memory.NewRepository(), cache.Options{TTL: time.Minute})
+	assert.Error(t, err)
+
+	_, err = cache.NewRepository(memory.NewRepository(), cache.Options{Size: 1})
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+
+	"shortener/internal/domain"
+)
+
+type entry struct {
+	key       string
+	link      domain.Link
+	notFound  bool
+	expiresAt time.Time
+}
+
+type lru struct {
+	size  int
+	order *list.List
+	items map[string]*list.Element
+}
+
+func newLRU(size int) *lru {
+	return &lru{
+		size:  size,
+		order: list.New(),
+		items: make(map[string]*list.Element, size),
+	}
+}
+
+func (l *lru) get(key string, now time.Time) (entry, bool) {
+	el, ok := l.items[key]
+	if !ok {
+		return entry{}, false
+	}
+
+	e := el.Value.(*entry)
+	if !now.Before(e.expiresAt) {
+		l.removeElement(el)
+		return entry{}, false
+	}
+
+	l.order.MoveToFront(el)
+
+	return *e, true
+}
+
+func (l *lru) add(e entry) {
+	if el, ok := l.items[e.key]; ok {
+		el.Value = &e
+		l.order.MoveToFront(el)
+		return
+	}
+
+	l.items[e.key] = l.order.PushFront(&e)
+
+	for l.order.Len() > l.size {
+		l.removeElement(l.order.Back())
+	}
+}
+
+func (l *lru) remove(key string) {
+	if el, ok := l.items[key]; ok {
+		l.removeElement(el)
+	}
+}
+
+func (l *lru) removeFunc(fn func(entry) bool) {
+	for el := l.order.Front(); el != nil; {
+		next := el.Next()
+		if fn(*el.Value.(*entry)) {
+			l.removeElement(el)
+		}
+
+		el = next
+	}
+}
+
+func (l *lru) len() int {
+	return l.order.Len()
+}
+
+func (l *lru) removeElement(el *list.Element) {
+	l.order.Remove(el)
+	delete(l.items, el.Value.(*entry).key)
+}
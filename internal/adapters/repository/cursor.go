@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"shortener/internal/domain"
+)
+
+// EncodeCursor packs the position of the last returned link: its creation
+// time and a storage-specific tie breaker.
+func EncodeCursor(createdAt time.Time, key string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + key
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", domain.ErrInvalidCursor, err)
+	}
+
+	nanos, key, ok := strings.Cut(string(raw), ":")
+	if !ok || key == "" {
+		return time.Time{}, "", domain.ErrInvalidCursor
+	}
+
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("%w: %v", domain.ErrInvalidCursor, err)
+	}
+
+	return time.Unix(0, n).UTC(), key, nil
+}
@@ -2,11 +2,30 @@ package repository
 
 import (
 	"context"
+	"time"
+
+	"shortener/internal/domain"
 )
 
 type Repository interface {
-	Save(ctx context.Context, original, shortened string) error
-	GetByShortened(ctx context.Context, shortened string) (string, error)
-	GetByOriginal(ctx context.Context, origin string) (string, error)
+	Save(ctx context.Context, link domain.Link) error
+	SaveBatch(ctx context.Context, links []domain.Link) ([]error, error)
+	GetByShortened(ctx context.Context, shortened string) (domain.Link, error)
+	GetByOriginal(ctx context.Context, origin string) (domain.Link, error)
+	Delete(ctx context.Context, shortened string) error
+	UpdateOriginal(ctx context.Context, shortened, original string) error
+	Replace(ctx context.Context, link domain.Link) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	SaveClicks(ctx context.Context, clicks []domain.Click) error
+	GetClickStats(ctx context.Context, shortened string) (domain.ClickStats, error)
+	GetAPIKey(ctx context.Context, hash string) (domain.APIKey, error)
+	NextBlock(ctx context.Context) (start, size uint64, err error)
+	ShortenedColumnLen(ctx context.Context) (int, error)
+	WidenShortenedColumn(ctx context.Context, size int) error
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key, value string) error
+	ForEachShortened(ctx context.Context, fn func(shortened string) error) error
+	ForEachLink(ctx context.Context, fn func(link domain.Link) error) error
+	List(ctx context.Context, filter domain.ListFilter) (domain.LinkPage, error)
 	Close()
 }
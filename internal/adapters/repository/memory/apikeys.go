@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"shortener/internal/domain"
+)
+
+func (r *MemoryRepository) LoadAPIKeys(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		name, hash, ok := strings.Cut(text, ":")
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if _, err := hex.DecodeString(hash); !ok || err != nil || len(hash) != 64 {
+			return fmt.Errorf("%s:%d: expected name:sha256hex", path, line)
+		}
+
+		r.AddAPIKey(domain.APIKey{
+			Name: strings.TrimSpace(name),
+			Hash: hash,
+		})
+	}
+
+	return scanner.Err()
+}
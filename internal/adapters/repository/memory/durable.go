@@ -0,0 +1,408 @@
+package memory
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	walFile      = "wal.jsonl"
+	lockFile     = "lock"
+
+	durableIDBlock = 1000
+)
+
+const (
+	opSave    = "save"
+	opDelete  = "delete"
+	opUpdate  = "update"
+	opReplace = "replace"
+	opExpire  = "expire"
+	opClicks  = "clicks"
+	opIDs     = "ids"
+	opSetting = "setting"
+)
+
+var ErrDirLocked = errors.New("memory data dir is used by another process")
+
+type record struct {
+	Seq       uint64         `json:"seq"`
+	Op        string         `json:"op"`
+	Links     []domain.Link  `json:"links,omitempty"`
+	Shortened string         `json:"shortened,omitempty"`
+	Original  string         `json:"original,omitempty"`
+	Before    time.Time      `json:"before,omitzero"`
+	Clicks    []domain.Click `json:"clicks,omitempty"`
+	NextID    uint64         `json:"next_id,omitempty"`
+	Key       string         `json:"key,omitempty"`
+	Value     string         `json:"value,omitempty"`
+}
+
+type snapshot struct {
+	Seq      uint64            `json:"seq"`
+	NextID   uint64            `json:"next_id"`
+	Links    []domain.Link     `json:"links"`
+	Clicks   []domain.Click    `json:"clicks"`
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+type wal struct {
+	f    *os.File
+	path string
+	seq  uint64
+	size int64
+}
+
+func (w *wal) append(rec record) error {
+	if w == nil {
+		return nil
+	}
+
+	rec.Seq = w.seq + 1
+
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	data = append(data, '\n')
+
+	if _, err = w.f.Write(data); err == nil {
+		err = w.f.Sync()
+	}
+
+	if err != nil {
+		_ = w.f.Truncate(w.size)
+		return fmt.Errorf("write-ahead log: %w", err)
+	}
+
+	w.seq = rec.Seq
+	w.size += int64(len(data))
+
+	return nil
+}
+
+// trim drops the first offset bytes, which a snapshot already covers. The
+// rest is copied to a new file that replaces the log by rename, so a crash
+// leaves either the old log or the new one.
+func (w *wal) trim(offset int64) error {
+	// the renamed file keeps the temporary name, so w.f.Name() won't do
+	tmp := w.path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, io.NewSectionReader(w.f, offset, w.size-offset))
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if err == nil {
+		err = os.Rename(tmp, w.path)
+	}
+
+	if err == nil {
+		err = syncDir(filepath.Dir(w.path))
+	}
+
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	_ = w.f.Close()
+	w.f = f
+	w.size -= offset
+
+	return nil
+}
+
+func (w *wal) close() {
+	if w == nil {
+		return
+	}
+
+	_ = w.f.Close()
+}
+
+// NewDurableRepository restores the repository from the snapshot and the
+// write-ahead log in dir and logs every following change there. log reports
+// the final snapshot failing on Close.
+func NewDurableRepository(dir string, log logger.Logger) (*MemoryRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	lock, err := lockDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := openDurable(dir)
+	if err != nil {
+		unlockDir(lock)
+		return nil, err
+	}
+
+	r.lock = lock
+	r.log = log
+
+	return r, nil
+}
+
+func openDurable(dir string) (*MemoryRepository, error) {
+	r := NewRepository()
+	r.dir = dir
+
+	seq, err := r.loadSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	seq, size, err := r.replay(f, seq)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	if err = f.Truncate(size); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	r.wal = &wal{f: f, path: f.Name(), seq: seq, size: size}
+
+	return r, nil
+}
+
+func unlockDir(lock *os.File) {
+	if lock != nil {
+		_ = lock.Close()
+	}
+}
+
+func (r *MemoryRepository) loadSnapshot() (uint64, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, snapshotFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	var snap snapshot
+	if err = json.Unmarshal(data, &snap); err != nil {
+		return 0, fmt.Errorf("snapshot: %w", err)
+	}
+
+	for _, link := range snap.Links {
+		r.applySave(link)
+	}
+
+	r.applyClicks(snap.Clicks)
+	r.nextID.Store(snap.NextID)
+
+	for key, value := range snap.Settings {
+		r.settings[key] = value
+	}
+
+	return snap.Seq, nil
+}
+
+// replay applies log records newer than the snapshot. A torn last line left
+// by a crash mid-write is dropped; the returned size is where it starts.
+func (r *MemoryRepository) replay(f *os.File, seq uint64) (uint64, int64, error) {
+	reader := bufio.NewReader(f)
+
+	var size int64
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return seq, size, nil
+		}
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		var rec record
+		if err = json.Unmarshal(data, &rec); err != nil {
+			return 0, 0, fmt.Errorf("write-ahead log line %d: %w", line, err)
+		}
+
+		size += int64(len(data))
+
+		if rec.Seq <= seq {
+			continue
+		}
+
+		if err = r.apply(rec); err != nil {
+			return 0, 0, fmt.Errorf("write-ahead log line %d: %w", line, err)
+		}
+
+		seq = rec.Seq
+	}
+}
+
+func (r *MemoryRepository) apply(rec record) error {
+	switch rec.Op {
+	case opSave:
+		for _, link := range rec.Links {
+			r.applySave(link)
+		}
+	case opDelete:
+		r.applyDelete(rec.Shortened)
+	case opUpdate:
+		r.applyUpdate(rec.Shortened, rec.Original)
+	case opReplace:
+		for _, link := range rec.Links {
+			r.applyReplace(link)
+		}
+	case opExpire:
+		r.applyExpire(rec.Before)
+	case opClicks:
+		r.applyClicks(rec.Clicks)
+	case opIDs:
+		if rec.NextID > r.nextID.Load() {
+			r.nextID.Store(rec.NextID)
+		}
+	case opSetting:
+		r.settings[rec.Key] = rec.Value
+	default:
+		return fmt.Errorf("unknown operation %q", rec.Op)
+	}
+
+	return nil
+}
+
+// Snapshot writes the current state and drops the write-ahead log records it
+// covers. Writers are only blocked while the state is copied and while the
+// records logged during the write are carried over to the new log.
+func (r *MemoryRepository) Snapshot() error {
+	if r.wal == nil {
+		return nil
+	}
+
+	r.snapshotMu.Lock()
+	defer r.snapshotMu.Unlock()
+
+	r.mu.RLock()
+
+	snap := snapshot{
+		Seq:      r.wal.seq,
+		NextID:   r.nextID.Load(),
+		Links:    make([]domain.Link, 0, len(r.shorteneddRepo)),
+		Clicks:   make([]domain.Click, 0),
+		Settings: make(map[string]string, len(r.settings)),
+	}
+
+	for _, link := range r.shorteneddRepo {
+		snap.Links = append(snap.Links, link)
+	}
+
+	for _, clicks := range r.clicks {
+		snap.Clicks = append(snap.Clicks, clicks...)
+	}
+
+	for key, value := range r.settings {
+		snap.Settings[key] = value
+	}
+
+	covered := r.wal.size
+
+	r.mu.RUnlock()
+
+	if err := writeFileSync(filepath.Join(r.dir, snapshotFile), snap); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.wal.trim(covered); err != nil {
+		return fmt.Errorf("write-ahead log: %w", err)
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) RunSnapshots(ctx context.Context, interval time.Duration, log logger.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Snapshot(); err != nil {
+				log.Error("snapshot failed",
+					logger.Field{Key: "error", Value: err})
+			}
+		}
+	}
+}
+
+func writeFileSync(path string, v any) error {
+	tmp := path + ".tmp"
+
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	err = json.NewEncoder(w).Encode(v)
+	if err == nil {
+		err = w.Flush()
+	}
+
+	if err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	if err = os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(path string) error {
+	dir, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
@@ -0,0 +1,226 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shortener/internal/adapters/repository/memory"
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func reopen(t *testing.T, r *memory.MemoryRepository, dir string) *memory.MemoryRepository {
+	t.Helper()
+
+	r.Close()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func TestDurableRepositoryReplay(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	past := time.Now().Add(-time.Hour).UTC()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://c.com", Shortened: "ccc", ExpiresAt: &past}))
+
+	errs, err := r.SaveBatch(ctx, []domain.Link{
+		{Original: "https://d.com", Shortened: "ddd"},
+		{Original: "https://a.com", Shortened: "eee"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []error{nil, domain.ErrAlreadyExist}, errs)
+
+	require.NoError(t, r.UpdateOriginal(ctx, "aaa", "https://a.org"))
+	require.NoError(t, r.Delete(ctx, "bbb"))
+	require.NoError(t, r.Replace(ctx, domain.Link{Original: "https://d.org", Shortened: "ddd", PasswordHash: "hash"}))
+	require.NoError(t, r.SaveClicks(ctx, []domain.Click{{Shortened: "aaa", At: time.Now().UTC()}}))
+
+	deleted, err := r.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+
+	start, _, err := r.NextBlock(ctx)
+	require.NoError(t, err)
+
+	r = reopen(t, r, dir)
+
+	link, err := r.GetByShortened(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.org", link.Original)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	_, err = r.GetByOriginal(ctx, "https://a.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	link, err = r.GetByShortened(ctx, "ddd")
+	require.NoError(t, err)
+	assert.Equal(t, "https://d.org", link.Original)
+	assert.True(t, link.Protected())
+
+	_, err = r.GetByOriginal(ctx, "https://d.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	for _, code := range []string{"bbb", "ccc", "eee"} {
+		_, err = r.GetByShortened(ctx, code)
+		assert.ErrorIs(t, err, domain.ErrNotFound, code)
+	}
+
+	stats, err := r.GetClickStats(ctx, "aaa")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Total)
+
+	next, _, err := r.NextBlock(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, next, start)
+}
+
+func TestDurableRepositorySnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	require.NoError(t, r.Snapshot())
+
+	info, err := os.Stat(filepath.Join(dir, "wal.jsonl"))
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb"}))
+
+	r = reopen(t, r, dir)
+
+	for _, code := range []string{"aaa", "bbb"} {
+		_, err = r.GetByShortened(ctx, code)
+		assert.NoError(t, err, code)
+	}
+}
+
+func TestDurableRepositoryTornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	r.Close()
+
+	f, err := os.OpenFile(filepath.Join(dir, "wal.jsonl"), os.O_WRONLY|os.O_APPEND, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"seq":2,"op":"sa`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	r, err = memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb"}))
+
+	r = reopen(t, r, dir)
+
+	for _, code := range []string{"aaa", "bbb"} {
+		_, err = r.GetByShortened(ctx, code)
+		assert.NoError(t, err, code)
+	}
+}
+
+func TestDurableRepositoryLock(t *testing.T) {
+	dir := t.TempDir()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	_, err = memory.NewDurableRepository(dir, logger.NewNop())
+	assert.ErrorIs(t, err, memory.ErrDirLocked)
+
+	r = reopen(t, r, dir)
+
+	_, err = memory.NewDurableRepository(dir, logger.NewNop())
+	assert.ErrorIs(t, err, memory.ErrDirLocked)
+}
+
+func TestDurableRepositorySettings(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	require.NoError(t, r.SetSetting(ctx, "compat", "v1"))
+	require.NoError(t, r.Snapshot())
+	require.NoError(t, r.SetSetting(ctx, "other", "x"))
+
+	r = reopen(t, r, dir)
+
+	for key, want := range map[string]string{"compat": "v1", "other": "x"} {
+		value, err := r.GetSetting(ctx, key)
+		require.NoError(t, err, key)
+		assert.Equal(t, want, value)
+	}
+}
+
+func TestDurableRepositoryWritesDuringSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r, err := memory.NewDurableRepository(dir, logger.NewNop())
+	require.NoError(t, err)
+
+	const links = 200
+
+	done := make(chan error, 1)
+	go func() {
+		for i := range links {
+			code := fmt.Sprintf("code%d", i)
+			if err := r.Save(ctx, domain.Link{Original: "https://" + code + ".com", Shortened: code}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	for range 5 {
+		require.NoError(t, r.Snapshot())
+	}
+	require.NoError(t, <-done)
+
+	// copy the files as a crash would leave them, Close writes a fresh snapshot
+	crashed := t.TempDir()
+	for _, name := range []string{"snapshot.json", "wal.jsonl"} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(crashed, name), data, 0o644))
+	}
+	r.Close()
+
+	r, err = memory.NewDurableRepository(crashed, logger.NewNop())
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+
+	for i := range links {
+		code := fmt.Sprintf("code%d", i)
+		_, err = r.GetByShortened(ctx, code)
+		assert.NoError(t, err, code)
+	}
+}
@@ -2,64 +2,536 @@ package memory
 
 import (
 	"context"
+	"os"
+	"shortener/internal/adapters/repository"
 	"shortener/internal/domain"
+	"shortener/pkg/logger"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type MemoryRepository struct {
 	mu             sync.RWMutex
 	originalRepo   map[string]string
-	shorteneddRepo map[string]string
+	shorteneddRepo map[string]domain.Link
+	clicks         map[string][]domain.Click
+	apiKeys        map[string]domain.APIKey
+	settings       map[string]string
+	nextID         atomic.Uint64
+	wal            *wal
+	snapshotMu     sync.Mutex
+	dir            string
+	lock           *os.File
+	log            logger.Logger
 }
 
 func NewRepository() *MemoryRepository {
 	return &MemoryRepository{
 		mu:             sync.RWMutex{},
 		originalRepo:   make(map[string]string),
-		shorteneddRepo: make(map[string]string),
+		shorteneddRepo: make(map[string]domain.Link),
+		clicks:         make(map[string][]domain.Click),
+		apiKeys:        make(map[string]domain.APIKey),
+		settings:       make(map[string]string),
 	}
 }
 
-func (r *MemoryRepository) Save(_ context.Context, original, shortened string) error {
+func (r *MemoryRepository) Save(_ context.Context, link domain.Link) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, ok := r.originalRepo[original]; ok {
-		return domain.ErrAlreadyExist
+	if err := r.checkSave(link); err != nil {
+		return err
+	}
+
+	link = withCreatedAt(link)
+	if err := r.wal.append(record{Op: opSave, Links: []domain.Link{link}}); err != nil {
+		return err
+	}
+
+	r.applySave(link)
+
+	return nil
+}
+
+func (r *MemoryRepository) SaveBatch(_ context.Context, links []domain.Link) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	errs := make([]error, len(links))
+	accepted := make([]domain.Link, 0, len(links))
+	originals := make(map[string]struct{}, len(links))
+	shortened := make(map[string]struct{}, len(links))
+
+	for i, link := range links {
+		_, dupOriginal := originals[link.Original]
+		_, dupShortened := shortened[link.Shortened]
+		if dupOriginal || dupShortened {
+			errs[i] = domain.ErrAlreadyExist
+			continue
+		}
+
+		if errs[i] = r.checkSave(link); errs[i] != nil {
+			continue
+		}
+
+		originals[link.Original] = struct{}{}
+		shortened[link.Shortened] = struct{}{}
+		accepted = append(accepted, withCreatedAt(link))
 	}
 
-	if _, ok := r.shorteneddRepo[shortened]; ok {
+	if len(accepted) == 0 {
+		return errs, nil
+	}
+
+	if err := r.wal.append(record{Op: opSave, Links: accepted}); err != nil {
+		return nil, err
+	}
+
+	for _, link := range accepted {
+		r.applySave(link)
+	}
+
+	return errs, nil
+}
+
+func (r *MemoryRepository) checkSave(link domain.Link) error {
+	if _, ok := r.originalRepo[link.Original]; ok && link.Reusable() {
 		return domain.ErrAlreadyExist
 	}
 
-	r.originalRepo[original] = shortened
-	r.shorteneddRepo[shortened] = original
+	if _, ok := r.shorteneddRepo[link.Shortened]; ok {
+		return domain.ErrAlreadyExist
+	}
 
 	return nil
 }
 
-func (r *MemoryRepository) GetByShortened(_ context.Context, shortened string) (string, error) {
+func withCreatedAt(link domain.Link) domain.Link {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now().UTC()
+	}
+
+	return link
+}
+
+func (r *MemoryRepository) applySave(link domain.Link) {
+	if link.Reusable() {
+		r.originalRepo[link.Original] = link.Shortened
+	}
+	r.shorteneddRepo[link.Shortened] = link
+}
+
+func (r *MemoryRepository) unindexOriginal(link domain.Link) {
+	if r.originalRepo[link.Original] == link.Shortened {
+		delete(r.originalRepo, link.Original)
+	}
+}
+
+func (r *MemoryRepository) GetByShortened(_ context.Context, shortened string) (domain.Link, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	original, ok := r.shorteneddRepo[shortened]
+	link, ok := r.shorteneddRepo[shortened]
 	if !ok {
-		return "", domain.ErrNotFound
+		return domain.Link{}, domain.ErrNotFound
 	}
 
-	return original, nil
+	return link, nil
 }
 
-func (r *MemoryRepository) GetByOriginal(_ context.Context, original string) (string, error) {
+func (r *MemoryRepository) GetByOriginal(_ context.Context, original string) (domain.Link, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
 	shortened, ok := r.originalRepo[original]
+	if !ok {
+		return domain.Link{}, domain.ErrNotFound
+	}
+
+	return r.shorteneddRepo[shortened], nil
+}
+
+func (r *MemoryRepository) Delete(_ context.Context, shortened string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.shorteneddRepo[shortened]; !ok {
+		return domain.ErrNotFound
+	}
+
+	if err := r.wal.append(record{Op: opDelete, Shortened: shortened}); err != nil {
+		return err
+	}
+
+	r.applyDelete(shortened)
+
+	return nil
+}
+
+func (r *MemoryRepository) applyDelete(shortened string) {
+	link, ok := r.shorteneddRepo[shortened]
+	if !ok {
+		return
+	}
+
+	delete(r.shorteneddRepo, shortened)
+	r.unindexOriginal(link)
+	delete(r.clicks, shortened)
+}
+
+func (r *MemoryRepository) UpdateOriginal(_ context.Context, shortened, original string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.shorteneddRepo[shortened]
+	if !ok {
+		return domain.ErrNotFound
+	}
+
+	if owner, ok := r.originalRepo[original]; ok && link.Reusable() {
+		if owner == shortened {
+			return nil
+		}
+
+		return domain.ErrAlreadyExist
+	}
+
+	if err := r.wal.append(record{Op: opUpdate, Shortened: shortened, Original: original}); err != nil {
+		return err
+	}
+
+	r.applyUpdate(shortened, original)
+
+	return nil
+}
+
+func (r *MemoryRepository) applyUpdate(shortened, original string) {
+	link, ok := r.shorteneddRepo[shortened]
+	if !ok {
+		return
+	}
+
+	r.unindexOriginal(link)
+
+	link.Original = original
+	if link.Reusable() {
+		r.originalRepo[original] = shortened
+	}
+	r.shorteneddRepo[shortened] = link
+}
+
+func (r *MemoryRepository) Replace(_ context.Context, link domain.Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.shorteneddRepo[link.Shortened]
+	if !ok {
+		return domain.ErrNotFound
+	}
+
+	if owner, ok := r.originalRepo[link.Original]; ok && owner != link.Shortened && link.Reusable() {
+		return domain.ErrAlreadyExist
+	}
+
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = current.CreatedAt
+	}
+
+	if err := r.wal.append(record{Op: opReplace, Links: []domain.Link{link}}); err != nil {
+		return err
+	}
+
+	r.applyReplace(link)
+
+	return nil
+}
+
+func (r *MemoryRepository) applyReplace(link domain.Link) {
+	if current, ok := r.shorteneddRepo[link.Shortened]; ok {
+		r.unindexOriginal(current)
+	}
+
+	r.applySave(link)
+}
+
+func (r *MemoryRepository) DeleteExpired(_ context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	expired := false
+	for _, link := range r.shorteneddRepo {
+		if link.Expired(before) {
+			expired = true
+			break
+		}
+	}
+
+	if !expired {
+		return 0, nil
+	}
+
+	if err := r.wal.append(record{Op: opExpire, Before: before}); err != nil {
+		return 0, err
+	}
+
+	return r.applyExpire(before), nil
+}
+
+func (r *MemoryRepository) applyExpire(before time.Time) int64 {
+	var deleted int64
+	for shortened, link := range r.shorteneddRepo {
+		if !link.Expired(before) {
+			continue
+		}
+
+		delete(r.shorteneddRepo, shortened)
+		r.unindexOriginal(link)
+		delete(r.clicks, shortened)
+		deleted++
+	}
+
+	return deleted
+}
+
+func (r *MemoryRepository) SaveClicks(_ context.Context, clicks []domain.Click) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	known := make([]domain.Click, 0, len(clicks))
+	for _, click := range clicks {
+		if _, ok := r.shorteneddRepo[click.Shortened]; ok {
+			known = append(known, click)
+		}
+	}
+
+	if len(known) == 0 {
+		return nil
+	}
+
+	if err := r.wal.append(record{Op: opClicks, Clicks: known}); err != nil {
+		return err
+	}
+
+	r.applyClicks(known)
+
+	return nil
+}
+
+func (r *MemoryRepository) applyClicks(clicks []domain.Click) {
+	for _, click := range clicks {
+		if _, ok := r.shorteneddRepo[click.Shortened]; !ok {
+			continue
+		}
+
+		r.clicks[click.Shortened] = append(r.clicks[click.Shortened], click)
+	}
+}
+
+func (r *MemoryRepository) GetClickStats(_ context.Context, shortened string) (domain.ClickStats, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	perDay := make(map[time.Time]int64)
+	for _, click := range r.clicks[shortened] {
+		perDay[click.At.UTC().Truncate(24*time.Hour)]++
+	}
+
+	stats := domain.ClickStats{
+		Total: int64(len(r.clicks[shortened])),
+		Daily: make([]domain.DailyClicks, 0, len(perDay)),
+	}
+
+	for day, count := range perDay {
+		stats.Daily = append(stats.Daily, domain.DailyClicks{Day: day, Count: count})
+	}
+
+	sort.Slice(stats.Daily, func(i, j int) bool {
+		return stats.Daily[i].Day.Before(stats.Daily[j].Day)
+	})
+
+	return stats, nil
+}
+
+func (r *MemoryRepository) GetAPIKey(_ context.Context, hash string) (domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.apiKeys[hash]
+	if !ok {
+		return domain.APIKey{}, domain.ErrNotFound
+	}
+
+	return key, nil
+}
+
+func (r *MemoryRepository) AddAPIKey(key domain.APIKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.apiKeys[key.Hash] = key
+}
+
+func (r *MemoryRepository) NextBlock(_ context.Context) (uint64, uint64, error) {
+	if r.wal == nil {
+		return r.nextID.Add(1) - 1, 1, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start := r.nextID.Load()
+	next := start + durableIDBlock
+
+	if err := r.wal.append(record{Op: opIDs, NextID: next}); err != nil {
+		return 0, 0, err
+	}
+
+	r.nextID.Store(next)
+
+	return start, durableIDBlock, nil
+}
+
+func (r *MemoryRepository) ShortenedColumnLen(_ context.Context) (int, error) {
+	return 0, nil
+}
+
+func (r *MemoryRepository) WidenShortenedColumn(_ context.Context, _ int) error {
+	return nil
+}
+
+func (r *MemoryRepository) GetSetting(_ context.Context, key string) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	value, ok := r.settings[key]
 	if !ok {
 		return "", domain.ErrNotFound
 	}
 
-	return shortened, nil
+	return value, nil
+}
+
+func (r *MemoryRepository) SetSetting(_ context.Context, key, value string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.wal.append(record{Op: opSetting, Key: key, Value: value}); err != nil {
+		return err
+	}
+
+	r.settings[key] = value
+
+	return nil
+}
+
+func (r *MemoryRepository) ForEachShortened(_ context.Context, fn func(shortened string) error) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for shortened := range r.shorteneddRepo {
+		if err := fn(shortened); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *MemoryRepository) ForEachLink(_ context.Context, fn func(link domain.Link) error) error {
+	r.mu.RLock()
+	links := make([]domain.Link, 0, len(r.shorteneddRepo))
+	for _, link := range r.shorteneddRepo {
+		links = append(links, link)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool {
+		return linkBefore(links[i], links[j].CreatedAt, links[j].Shortened)
+	})
+
+	for _, link := range links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List orders links by creation time with the code as a tie breaker.
+func (r *MemoryRepository) List(_ context.Context, filter domain.ListFilter) (domain.LinkPage, error) {
+	var (
+		after    time.Time
+		afterKey string
+	)
+	if filter.Cursor != "" {
+		var err error
+		if after, afterKey, err = repository.DecodeCursor(filter.Cursor); err != nil {
+			return domain.LinkPage{}, err
+		}
+	}
+
+	r.mu.RLock()
+	links := make([]domain.Link, 0, filter.Limit+1)
+	for _, link := range r.shorteneddRepo {
+		if afterKey != "" {
+			if filter.Desc && !linkBefore(link, after, afterKey) {
+				continue
+			}
+			if !filter.Desc && (linkBefore(link, after, afterKey) || link.Shortened == afterKey) {
+				continue
+			}
+		}
+
+		if filter.Match(link) {
+			links = append(links, link)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(links, func(i, j int) bool {
+		if filter.Desc {
+			return linkBefore(links[j], links[i].CreatedAt, links[i].Shortened)
+		}
+
+		return linkBefore(links[i], links[j].CreatedAt, links[j].Shortened)
+	})
+
+	page := domain.LinkPage{Links: links}
+	if len(links) > filter.Limit {
+		page.Links = links[:filter.Limit]
+		last := page.Links[filter.Limit-1]
+		page.NextCursor = repository.EncodeCursor(last.CreatedAt, last.Shortened)
+	}
+
+	return page, nil
+}
+
+func linkBefore(link domain.Link, createdAt time.Time, shortened string) bool {
+	if !link.CreatedAt.Equal(createdAt) {
+		return link.CreatedAt.Before(createdAt)
+	}
+
+	return link.Shortened < shortened
 }
 
-func (r *MemoryRepository) Close() {}
+func (r *MemoryRepository) Close() {
+	// the log still holds every change, so a failed snapshot loses nothing
+	// but makes the next start replay more
+	if err := r.Snapshot(); err != nil && r.log != nil {
+		r.log.Error("final snapshot failed",
+			logger.Field{Key: "dir", Value: r.dir},
+			logger.Field{Key: "error", Value: err})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.wal.close()
+
+	unlockDir(r.lock)
+	r.lock = nil
+}
@@ -0,0 +1,134 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"shortener/internal/adapters/repository/memory"
+	"shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtectedLinks(t *testing.T) {
+	ctx := context.Background()
+	r := memory.NewRepository()
+	future := time.Now().Add(time.Hour)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "bbb", PasswordHash: "hash"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "eee", ExpiresAt: &future}))
+	assert.ErrorIs(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "ccc"}), domain.ErrAlreadyExist)
+
+	link, err := r.GetByOriginal(ctx, "https://a.com")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", link.Shortened)
+
+	require.NoError(t, r.UpdateOriginal(ctx, "bbb", "https://b.com"))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "ddd"}))
+
+	require.NoError(t, r.Delete(ctx, "bbb"))
+	link, err = r.GetByOriginal(ctx, "https://b.com")
+	require.NoError(t, err)
+	assert.Equal(t, "ddd", link.Shortened)
+}
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	r := memory.NewRepository()
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb"}))
+	require.NoError(t, r.SaveClicks(ctx, []domain.Click{{Shortened: "aaa"}}))
+
+	assert.ErrorIs(t, r.Replace(ctx, domain.Link{Original: "https://b.com", Shortened: "aaa"}), domain.ErrAlreadyExist)
+	assert.ErrorIs(t, r.Replace(ctx, domain.Link{Original: "https://c.com", Shortened: "missing"}), domain.ErrNotFound)
+	require.NoError(t, r.Replace(ctx, domain.Link{Original: "https://c.com", Shortened: "aaa"}))
+
+	_, err := r.GetByOriginal(ctx, "https://a.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	link, err := r.GetByOriginal(ctx, "https://c.com")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", link.Shortened)
+	assert.False(t, link.CreatedAt.IsZero())
+
+	stats, err := r.GetClickStats(ctx, "aaa")
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, stats.Total)
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	r := memory.NewRepository()
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	originals := []string{
+		"https://example.com/a",
+		"https://docs.example.com/b",
+		"https://other.org/example",
+		"https://notexample.com/c",
+		"https://example.com/d",
+	}
+	for i, original := range originals {
+		require.NoError(t, r.Save(ctx, domain.Link{
+			Original:  original,
+			Shortened: fmt.Sprintf("code%d", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	codes := func(page domain.LinkPage) []string {
+		out := make([]string, 0, len(page.Links))
+		for _, link := range page.Links {
+			out = append(out, link.Shortened)
+		}
+
+		return out
+	}
+
+	page, err := r.List(ctx, domain.ListFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code0", "code1"}, codes(page))
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code2", "code3"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code4"}, codes(page))
+	assert.Empty(t, page.NextCursor)
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 3, Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code4", "code3", "code2"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 1, Desc: true, Domain: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code4"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 1, Desc: true, Domain: "example.com", Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code1"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 10, Query: "EXAMPLE/"})
+	require.NoError(t, err)
+	assert.Empty(t, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 10, Query: "Example"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code0", "code1", "code2", "code3", "code4"}, codes(page))
+
+	from, before := base.Add(time.Hour), base.Add(3*time.Hour)
+	page, err = r.List(ctx, domain.ListFilter{Limit: 10, CreatedFrom: &from, CreatedBefore: &before})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code1", "code2"}, codes(page))
+
+	_, err = r.List(ctx, domain.ListFilter{Limit: 10, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
@@ -0,0 +1,249 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// migrationLockID keys the advisory lock that serialises migration runs
+// across instances starting at the same time.
+const migrationLockID = 7265431
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type migration struct {
+	version int
+	name    string
+	up      string
+	down    string
+}
+
+type MigrationStatus struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+func loadMigrations() ([]migration, error) {
+	return readMigrations(migrationFiles)
+}
+
+// readMigrations pairs NNN_name.up.sql and NNN_name.down.sql files from the
+// migrations directory of fsys, sorted by version.
+func readMigrations(fsys fs.FS) ([]migration, error) {
+	names, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, path := range names {
+		file := strings.TrimPrefix(path, "migrations/")
+
+		prefix, rest, ok := strings.Cut(file, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: bad version", file)
+		}
+
+		data, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{version: version}
+			byVersion[version] = m
+		}
+
+		switch {
+		case strings.HasSuffix(rest, ".up.sql"):
+			m.name = strings.TrimSuffix(rest, ".up.sql")
+			m.up = string(data)
+		case strings.HasSuffix(rest, ".down.sql"):
+			m.down = string(data)
+		default:
+			return nil, fmt.Errorf("migration %s: expected .up.sql or .down.sql", file)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" {
+			return nil, fmt.Errorf("migration %03d: missing up file", m.version)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+func (r *PostgresRepository) MigrateUp(ctx context.Context) error {
+	return r.withMigrationLock(ctx, func(conn *pgxpool.Conn, current int, migrations []migration) error {
+		for _, m := range migrations {
+			if m.version <= current {
+				continue
+			}
+
+			if err := applyMigration(ctx, conn, m.up, m.version); err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", m.version, m.name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresRepository) MigrateDown(ctx context.Context, steps int) error {
+	return r.withMigrationLock(ctx, func(conn *pgxpool.Conn, current int, migrations []migration) error {
+		for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+			m := migrations[i]
+			if m.version > current {
+				continue
+			}
+
+			if m.down == "" {
+				return fmt.Errorf("migration %03d_%s: missing down file", m.version, m.name)
+			}
+
+			previous := 0
+			if i > 0 {
+				previous = migrations[i-1].version
+			}
+
+			if err := applyMigration(ctx, conn, m.down, previous); err != nil {
+				return fmt.Errorf("migration %03d_%s: %w", m.version, m.name, err)
+			}
+
+			steps--
+		}
+
+		return nil
+	})
+}
+
+func (r *PostgresRepository) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	current, err := currentVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		statuses = append(statuses, MigrationStatus{
+			Version: m.version,
+			Name:    m.name,
+			Applied: m.version <= current,
+		})
+	}
+
+	return statuses, nil
+}
+
+func (r *PostgresRepository) withMigrationLock(ctx context.Context, fn func(*pgxpool.Conn, int, []migration) error) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := r.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `select pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), `select pg_advisory_unlock($1)`, migrationLockID)
+
+	current, err := currentVersion(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, current, migrations)
+}
+
+// currentVersion reads the schema_migrations table in the format used by
+// golang-migrate, so databases migrated by the migrate/migrate image keep working.
+func currentVersion(ctx context.Context, conn *pgxpool.Conn) (int, error) {
+	query := `
+	create table if not exists schema_migrations (
+		version bigint not null primary key,
+		dirty boolean not null
+	)
+`
+	if _, err := conn.Exec(ctx, query); err != nil {
+		return 0, err
+	}
+
+	var version int64
+	var dirty bool
+
+	err := conn.QueryRow(ctx, `select version, dirty from schema_migrations limit 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, nil
+		}
+
+		return 0, err
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("schema is dirty at version %d, fix it manually before migrating", version)
+	}
+
+	return int(version), nil
+}
+
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, query string, version int) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, query); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(ctx, `delete from schema_migrations`); err != nil {
+		return err
+	}
+
+	if version > 0 {
+		if _, err = tx.Exec(ctx, `insert into schema_migrations (version, dirty) values ($1, false)`, version); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
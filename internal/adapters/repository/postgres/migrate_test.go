@@ -0,0 +1,85 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/002_add_column.up.sql":     {Data: []byte("alter table t add column c int;")},
+		"migrations/001_create_table.up.sql":   {Data: []byte("create table t ();")},
+		"migrations/001_create_table.down.sql": {Data: []byte("drop table t;")},
+	}
+
+	migrations, err := readMigrations(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+
+	assert.Equal(t, 1, migrations[0].version)
+	assert.Equal(t, "create_table", migrations[0].name)
+	assert.Equal(t, "create table t ();", migrations[0].up)
+	assert.Equal(t, "drop table t;", migrations[0].down)
+
+	assert.Equal(t, 2, migrations[1].version)
+	assert.Equal(t, "add_column", migrations[1].name)
+	assert.Empty(t, migrations[1].down)
+}
+
+func TestReadMigrationsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		wantErr string
+	}{
+		{
+			name: "missing up file",
+			fsys: fstest.MapFS{
+				"migrations/001_create_table.up.sql": {Data: []byte("create table t ();")},
+				"migrations/002_add_column.down.sql": {Data: []byte("alter table t drop column c;")},
+			},
+			wantErr: "migration 002: missing up file",
+		},
+		{
+			name: "bad prefix",
+			fsys: fstest.MapFS{
+				"migrations/first_create_table.up.sql": {Data: []byte("create table t ();")},
+			},
+			wantErr: "migration first_create_table.up.sql: bad version",
+		},
+		{
+			name: "no prefix",
+			fsys: fstest.MapFS{
+				"migrations/001.up.sql": {Data: []byte("create table t ();")},
+			},
+			wantErr: "migration 001.up.sql: bad version",
+		},
+		{
+			name: "unknown direction",
+			fsys: fstest.MapFS{
+				"migrations/001_create_table.sql": {Data: []byte("create table t ();")},
+			},
+			wantErr: "migration 001_create_table.sql: expected .up.sql or .down.sql",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMigrations(tt.fsys)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := loadMigrations()
+	require.NoError(t, err)
+
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.version, "versions must be consecutive")
+		assert.NotEmpty(t, m.down, "migration %03d_%s has no down file", m.version, m.name)
+	}
+}
@@ -0,0 +1 @@
+alter table urls alter column shortened type varchar(10);
@@ -0,0 +1,3 @@
+drop index if exists urls_expires_at_idx;
+
+alter table urls drop column if exists expires_at;
@@ -0,0 +1,3 @@
+alter table urls add column if not exists expires_at timestamptz;
+
+create index if not exists urls_expires_at_idx on urls (expires_at) where expires_at is not null;
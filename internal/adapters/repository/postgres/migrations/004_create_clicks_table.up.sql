@@ -0,0 +1,10 @@
+create table if not exists clicks (
+    id bigserial primary key,
+    shortened varchar(32) not null,
+    clicked_at timestamptz not null,
+    referrer text not null default '',
+    user_agent text not null default '',
+    ip text not null default ''
+);
+
+create index if not exists clicks_shortened_clicked_at_idx on clicks (shortened, clicked_at);
@@ -0,0 +1,7 @@
+create table if not exists api_keys (
+    id serial primary key,
+    name text not null,
+    key_hash char(64) not null unique,
+    created_at timestamp not null default now(),
+    revoked_at timestamptz
+);
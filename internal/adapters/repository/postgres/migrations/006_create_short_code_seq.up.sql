@@ -0,0 +1,5 @@
+create sequence if not exists short_code_seq
+    as bigint
+    minvalue 0
+    start with 0
+    increment by 1000;
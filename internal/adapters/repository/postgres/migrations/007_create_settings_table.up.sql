@@ -0,0 +1,4 @@
+create table if not exists settings (
+    key text primary key,
+    value text not null
+);
@@ -0,0 +1,15 @@
+-- without the hash protected links would resolve for anyone, and deleting them
+-- silently loses data, so refuse to roll back until they are handled by hand
+do $$
+begin
+    if exists (select 1 from urls where password_hash is not null) then
+        raise exception 'urls has password protected links, delete or export them before rolling back 008_add_password_hash';
+    end if;
+end
+$$;
+
+drop index if exists urls_original_public_idx;
+
+alter table urls drop column if exists password_hash;
+
+alter table urls add constraint urls_original_key unique (original);
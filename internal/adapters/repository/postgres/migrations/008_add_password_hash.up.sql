@@ -0,0 +1,5 @@
+alter table urls add column if not exists password_hash text;
+
+alter table urls drop constraint if exists urls_original_key;
+
+create unique index if not exists urls_original_public_idx on urls (original) where password_hash is null;
@@ -0,0 +1 @@
+drop index if exists urls_created_at_id_idx;
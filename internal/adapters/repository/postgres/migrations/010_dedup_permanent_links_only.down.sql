@@ -0,0 +1,18 @@
+-- after 010 public links with an expiry may share an original, which the old
+-- index forbids, so refuse to roll back instead of failing on a duplicate key
+do $$
+begin
+    if exists (
+        select 1 from urls
+        where password_hash is null
+        group by original
+        having count(*) > 1
+    ) then
+        raise exception 'urls has public links sharing an original, delete the duplicates before rolling back 010_dedup_permanent_links_only';
+    end if;
+end
+$$;
+
+drop index if exists urls_original_public_idx;
+
+create unique index if not exists urls_original_public_idx on urls (original) where password_hash is null;
@@ -0,0 +1,3 @@
+drop index if exists urls_original_public_idx;
+
+create unique index if not exists urls_original_public_idx on urls (original) where password_hash is null and expires_at is null;
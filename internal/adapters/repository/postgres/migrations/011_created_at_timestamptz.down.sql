@@ -0,0 +1,3 @@
+alter table api_keys alter column created_at type timestamp using created_at at time zone 'utc';
+
+alter table urls alter column created_at type timestamp using created_at at time zone 'utc';
@@ -0,0 +1,6 @@
+-- created_at had no time zone, so now() stored it in the session time zone;
+-- keep it as an instant like expires_at. Existing values are read as UTC, which
+-- is what the service wrote itself and what now() wrote under a UTC server
+alter table urls alter column created_at type timestamptz using created_at at time zone 'utc';
+
+alter table api_keys alter column created_at type timestamptz using created_at at time zone 'utc';
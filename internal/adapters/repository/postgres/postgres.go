@@ -2,16 +2,27 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"shortener/config"
+	"shortener/internal/adapters/repository"
 	"shortener/internal/domain"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/jackc/pgconn"
-	"github.com/jackc/pgx"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-const errCodeAlreadyExist = "23505"
+const (
+	errCodeAlreadyExist = "23505"
+
+	pageSize = 1000
+)
+
+var ErrSchemaMissing = errors.New("urls table missing, run `app migrate up` or set DB_AUTO_MIGRATE=true")
 
 type PostgresRepository struct {
 	pool *pgxpool.Pool
@@ -35,6 +46,7 @@ func NewRepository(ctx context.Context, config config.Postgres) (*PostgresReposi
 
 	poolCfg.MaxConns = int32(config.MaxConns)
 	poolCfg.MinConns = int32(config.MinConns)
+	poolCfg.ConnConfig.Tracer = newQueryTracer()
 
 	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
 	if err != nil {
@@ -42,22 +54,33 @@ func NewRepository(ctx context.Context, config config.Postgres) (*PostgresReposi
 	}
 
 	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
 		return nil, err
 	}
 
-	return &PostgresRepository{
+	r := &PostgresRepository{
 		pool: pool,
-	}, nil
+	}
+
+	if config.AutoMigrate {
+		if err := r.MigrateUp(ctx); err != nil {
+			pool.Close()
+			return nil, err
+		}
+	}
+
+	return r, nil
 }
 
-func (r *PostgresRepository) Save(ctx context.Context, original, shortened string) error {
+func (r *PostgresRepository) Save(ctx context.Context, link domain.Link) error {
 	query := `
-	insert into urls(original, shortened)
-	values ($1, $2)
+	insert into urls(original, shortened, expires_at, password_hash, created_at)
+	values ($1, $2, $3, nullif($4, ''), coalesce($5::timestamptz, now()))
 `
-	_, err := r.pool.Exec(ctx, query, original, shortened)
+	_, err := r.pool.Exec(ctx, query, link.Original, link.Shortened, link.ExpiresAt, link.PasswordHash, createdAt(link))
 	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
 			if pgErr.Code == errCodeAlreadyExist {
 				return domain.ErrAlreadyExist
 			}
@@ -69,27 +92,256 @@ func (r *PostgresRepository) Save(ctx context.Context, original, shortened strin
 	return nil
 }
 
-func (r *PostgresRepository) GetByShortened(ctx context.Context, shortened string) (string, error) {
-	query := `select original from urls where shortened = $1`
+func (r *PostgresRepository) SaveBatch(ctx context.Context, links []domain.Link) ([]error, error) {
+	query := `
+	insert into urls(original, shortened, expires_at)
+	values ($1, $2, $3)
+	on conflict do nothing
+`
+	batch := &pgx.Batch{}
+	for _, link := range links {
+		batch.Queue(query, link.Original, link.Shortened, link.ExpiresAt)
+	}
+
+	br := r.pool.SendBatch(ctx, batch)
+	defer br.Close()
+
+	errs := make([]error, len(links))
+	for i := range links {
+		tag, err := br.Exec()
+		if err != nil {
+			return nil, err
+		}
+
+		if tag.RowsAffected() == 0 {
+			errs[i] = domain.ErrAlreadyExist
+		}
+	}
+
+	return errs, br.Close()
+}
+
+func (r *PostgresRepository) GetByShortened(ctx context.Context, shortened string) (domain.Link, error) {
+	query := `
+	select original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where shortened = $1
+`
+
+	return r.getLink(ctx, query, shortened)
+}
 
-	original := ""
-	err := r.pool.QueryRow(ctx, query, shortened).Scan(&original)
+func (r *PostgresRepository) GetByOriginal(ctx context.Context, origin string) (domain.Link, error) {
+	query := `
+	select original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where original = $1 and password_hash is null and expires_at is null
+`
+
+	return r.getLink(ctx, query, origin)
+}
+
+func (r *PostgresRepository) getLink(ctx context.Context, query string, arg string) (domain.Link, error) {
+	link := domain.Link{}
+	err := r.pool.QueryRow(ctx, query, arg).Scan(&link.Original, &link.Shortened, &link.CreatedAt, &link.ExpiresAt, &link.PasswordHash)
 	if err != nil {
 		if err.Error() == pgx.ErrNoRows.Error() {
-			return "", domain.ErrNotFound
+			return domain.Link{}, domain.ErrNotFound
 		}
 
-		return "", err
+		return domain.Link{}, err
+	}
+
+	return link, nil
+}
+
+func (r *PostgresRepository) Delete(ctx context.Context, shortened string) error {
+	query := `
+	with link_clicks as (
+		delete from clicks where shortened = $1
+	)
+	delete from urls where shortened = $1
+`
+	tag, err := r.pool.Exec(ctx, query, shortened)
+	if err != nil {
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) UpdateOriginal(ctx context.Context, shortened, original string) error {
+	query := `update urls set original = $1 where shortened = $2`
+
+	tag, err := r.pool.Exec(ctx, query, original, shortened)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == errCodeAlreadyExist {
+				return domain.ErrAlreadyExist
+			}
+		}
+
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) Replace(ctx context.Context, link domain.Link) error {
+	query := `
+	update urls
+	set original = $1, expires_at = $2, password_hash = nullif($3, ''), created_at = coalesce($4::timestamptz, created_at)
+	where shortened = $5
+`
+	tag, err := r.pool.Exec(ctx, query, link.Original, link.ExpiresAt, link.PasswordHash, createdAt(link), link.Shortened)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == errCodeAlreadyExist {
+				return domain.ErrAlreadyExist
+			}
+		}
+
+		return err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *PostgresRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+	with expired_clicks as (
+		delete from clicks
+		where shortened in (select shortened from urls where expires_at <= $1)
+	)
+	delete from urls where expires_at <= $1
+`
+	tag, err := r.pool.Exec(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (r *PostgresRepository) SaveClicks(ctx context.Context, clicks []domain.Click) error {
+	_, err := r.pool.CopyFrom(ctx,
+		pgx.Identifier{"clicks"},
+		[]string{"shortened", "clicked_at", "referrer", "user_agent", "ip"},
+		pgx.CopyFromSlice(len(clicks), func(i int) ([]any, error) {
+			c := clicks[i]
+			return []any{c.Shortened, c.At, c.Referrer, c.UserAgent, c.IP}, nil
+		}),
+	)
+
+	return err
+}
+
+func (r *PostgresRepository) GetClickStats(ctx context.Context, shortened string) (domain.ClickStats, error) {
+	query := `
+	select (clicked_at at time zone 'UTC')::date as day, count(*)
+	from clicks
+	where shortened = $1
+	group by day
+	order by day
+`
+	rows, err := r.pool.Query(ctx, query, shortened)
+	if err != nil {
+		return domain.ClickStats{}, err
+	}
+	defer rows.Close()
+
+	stats := domain.ClickStats{Daily: []domain.DailyClicks{}}
+	for rows.Next() {
+		daily := domain.DailyClicks{}
+		if err := rows.Scan(&daily.Day, &daily.Count); err != nil {
+			return domain.ClickStats{}, err
+		}
+
+		stats.Total += daily.Count
+		stats.Daily = append(stats.Daily, daily)
 	}
 
-	return original, nil
+	return stats, rows.Err()
 }
 
-func (r *PostgresRepository) GetByOriginal(ctx context.Context, origin string) (string, error) {
-	query := `select shortened from urls where original = $1`
+func (r *PostgresRepository) GetAPIKey(ctx context.Context, hash string) (domain.APIKey, error) {
+	query := `select name, key_hash from api_keys where key_hash = $1 and revoked_at is null`
 
-	shortened := ""
-	err := r.pool.QueryRow(ctx, query, origin).Scan(&shortened)
+	key := domain.APIKey{}
+	err := r.pool.QueryRow(ctx, query, hash).Scan(&key.Name, &key.Hash)
+	if err != nil {
+		if err.Error() == pgx.ErrNoRows.Error() {
+			return domain.APIKey{}, domain.ErrNotFound
+		}
+
+		return domain.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (r *PostgresRepository) NextBlock(ctx context.Context) (uint64, uint64, error) {
+	query := `
+	select nextval('short_code_seq'), increment_by
+	from pg_sequences
+	where schemaname = current_schema() and sequencename = 'short_code_seq'
+`
+	var start, size int64
+	if err := r.pool.QueryRow(ctx, query).Scan(&start, &size); err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(start), uint64(size), nil
+}
+
+func (r *PostgresRepository) ShortenedColumnLen(ctx context.Context) (int, error) {
+	query := `
+	select coalesce(character_maximum_length, 0)
+	from information_schema.columns
+	where table_schema = current_schema() and table_name = 'urls' and column_name = 'shortened'
+`
+	var size int
+	if err := r.pool.QueryRow(ctx, query).Scan(&size); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrSchemaMissing
+		}
+
+		return 0, err
+	}
+
+	return size, nil
+}
+
+func (r *PostgresRepository) WidenShortenedColumn(ctx context.Context, size int) error {
+	query := fmt.Sprintf(`
+	alter table urls alter column shortened type varchar(%[1]d);
+	alter table clicks alter column shortened type varchar(%[1]d);
+`, size)
+
+	_, err := r.pool.Exec(ctx, query)
+
+	return err
+}
+
+func (r *PostgresRepository) GetSetting(ctx context.Context, key string) (string, error) {
+	query := `select value from settings where key = $1`
+
+	var value string
+	err := r.pool.QueryRow(ctx, query, key).Scan(&value)
 	if err != nil {
 		if err.Error() == pgx.ErrNoRows.Error() {
 			return "", domain.ErrNotFound
@@ -98,9 +350,185 @@ func (r *PostgresRepository) GetByOriginal(ctx context.Context, origin string) (
 		return "", err
 	}
 
-	return shortened, nil
+	return value, nil
+}
+
+func (r *PostgresRepository) SetSetting(ctx context.Context, key, value string) error {
+	query := `
+	insert into settings(key, value)
+	values ($1, $2)
+	on conflict (key) do update set value = excluded.value
+`
+	_, err := r.pool.Exec(ctx, query, key, value)
+
+	return err
+}
+
+func (r *PostgresRepository) ForEachShortened(ctx context.Context, fn func(shortened string) error) error {
+	rows, err := r.pool.Query(ctx, `select shortened from urls`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortened string
+		if err := rows.Scan(&shortened); err != nil {
+			return err
+		}
+
+		if err := fn(shortened); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *PostgresRepository) ForEachLink(ctx context.Context, fn func(link domain.Link) error) error {
+	query := `
+	select id, original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where id > $1
+	order by id
+	limit $2
+`
+	var lastID int64
+	for {
+		links, err := r.linksPage(ctx, query, &lastID)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			if err := fn(link); err != nil {
+				return err
+			}
+		}
+
+		if len(links) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (r *PostgresRepository) linksPage(ctx context.Context, query string, lastID *int64) ([]domain.Link, error) {
+	rows, err := r.pool.Query(ctx, query, *lastID, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]domain.Link, 0, pageSize)
+	for rows.Next() {
+		link := domain.Link{}
+		if err := rows.Scan(lastID, &link.Original, &link.Shortened, &link.CreatedAt, &link.ExpiresAt, &link.PasswordHash); err != nil {
+			return nil, err
+		}
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// List pages through links by (created_at, id). Domain matching is only
+// narrowed down in SQL and finished by filter.Match.
+func (r *PostgresRepository) List(ctx context.Context, filter domain.ListFilter) (domain.LinkPage, error) {
+	var (
+		after   *time.Time
+		afterID int64
+	)
+	if filter.Cursor != "" {
+		createdAt, key, err := repository.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.LinkPage{}, err
+		}
+
+		if afterID, err = strconv.ParseInt(key, 10, 64); err != nil {
+			return domain.LinkPage{}, domain.ErrInvalidCursor
+		}
+		after = &createdAt
+	}
+
+	cmp, direction := ">", "asc"
+	if filter.Desc {
+		cmp, direction = "<", "desc"
+	}
+
+	query := fmt.Sprintf(`
+	select id, original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where ($1::timestamptz is null or (created_at, id) %[1]s ($1, $2))
+		and ($3::timestamptz is null or created_at >= $3)
+		and ($4::timestamptz is null or created_at < $4)
+		and ($5 = '' or strpos(lower(original), $5) > 0 or strpos(lower(shortened), $5) > 0)
+		and ($6 = '' or strpos(lower(original), $6) > 0)
+	order by created_at %[2]s, id %[2]s
+	limit $7
+`, cmp, direction)
+
+	page := domain.LinkPage{Links: make([]domain.Link, 0, filter.Limit)}
+	var lastID int64
+	for {
+		rows, err := r.pool.Query(ctx, query, after, afterID, filter.CreatedFrom, filter.CreatedBefore,
+			strings.ToLower(filter.Query), strings.ToLower(filter.Domain), filter.Limit+1)
+		if err != nil {
+			return domain.LinkPage{}, err
+		}
+
+		scanned := 0
+		for rows.Next() {
+			var id int64
+			link := domain.Link{}
+			if err := rows.Scan(&id, &link.Original, &link.Shortened, &link.CreatedAt, &link.ExpiresAt, &link.PasswordHash); err != nil {
+				rows.Close()
+				return domain.LinkPage{}, err
+			}
+
+			scanned++
+			createdAt := link.CreatedAt
+			after, afterID = &createdAt, id
+
+			if !filter.Match(link) {
+				continue
+			}
+
+			if len(page.Links) == filter.Limit {
+				rows.Close()
+				last := page.Links[len(page.Links)-1]
+				page.NextCursor = repository.EncodeCursor(last.CreatedAt, strconv.FormatInt(lastID, 10))
+				return page, nil
+			}
+
+			page.Links = append(page.Links, link)
+			lastID = id
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return domain.LinkPage{}, err
+		}
+
+		if scanned <= filter.Limit {
+			return page, nil
+		}
+	}
+}
+
+func (r *PostgresRepository) Stat() *pgxpool.Stat {
+	return r.pool.Stat()
 }
 
 func (r *PostgresRepository) Close() {
 	r.pool.Close()
 }
+
+func createdAt(link domain.Link) *time.Time {
+	if link.CreatedAt.IsZero() {
+		return nil
+	}
+
+	t := link.CreatedAt.UTC()
+	return &t
+}
@@ -0,0 +1,81 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.up.sql
+var migrations embed.FS
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, `
+	create table if not exists schema_migrations (
+		version integer primary key,
+		applied_at timestamp not null default current_timestamp
+	)
+`); err != nil {
+		return err
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, `select coalesce(max(version), 0) from schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	names, err := fs.Glob(migrations, "migrations/*.up.sql")
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		base := strings.TrimPrefix(name, "migrations/")
+
+		prefix, _, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %s: bad version", base)
+		}
+
+		if version <= current {
+			continue
+		}
+
+		query, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		if err = apply(ctx, db, version, string(query)); err != nil {
+			return fmt.Errorf("migration %s: %w", base, err)
+		}
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, version int, query string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, query); err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, `insert into schema_migrations (version) values (?)`, version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
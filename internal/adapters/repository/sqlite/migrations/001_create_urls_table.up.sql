@@ -0,0 +1,9 @@
+create table if not exists urls (
+    id integer primary key autoincrement,
+    original text not null unique,
+    shortened varchar(32) not null unique,
+    created_at timestamp not null,
+    expires_at timestamp
+);
+
+create index if not exists urls_expires_at_idx on urls (expires_at) where expires_at is not null;
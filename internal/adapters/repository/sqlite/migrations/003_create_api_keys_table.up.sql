@@ -0,0 +1,7 @@
+create table if not exists api_keys (
+    id integer primary key autoincrement,
+    name text not null,
+    key_hash char(64) not null unique,
+    created_at timestamp not null default current_timestamp,
+    revoked_at timestamp
+);
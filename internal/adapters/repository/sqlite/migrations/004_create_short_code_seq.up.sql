@@ -0,0 +1,8 @@
+create table if not exists short_code_seq (
+    next_id integer not null,
+    increment_by integer not null
+);
+
+insert into short_code_seq (next_id, increment_by)
+select 0, 1000
+where not exists (select 1 from short_code_seq);
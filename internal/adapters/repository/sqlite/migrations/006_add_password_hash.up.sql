@@ -0,0 +1,19 @@
+create table urls_new (
+    id integer primary key autoincrement,
+    original text not null,
+    shortened varchar(32) not null unique,
+    created_at timestamp not null,
+    expires_at timestamp,
+    password_hash text
+);
+
+insert into urls_new (id, original, shortened, created_at, expires_at)
+select id, original, shortened, created_at, expires_at from urls;
+
+drop table urls;
+
+alter table urls_new rename to urls;
+
+create index if not exists urls_expires_at_idx on urls (expires_at) where expires_at is not null;
+
+create unique index if not exists urls_original_public_idx on urls (original) where password_hash is null;
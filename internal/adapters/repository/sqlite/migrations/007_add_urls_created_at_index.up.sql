@@ -0,0 +1 @@
+create index if not exists urls_created_at_id_idx on urls (created_at, id);
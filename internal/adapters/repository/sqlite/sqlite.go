@@ -0,0 +1,589 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"shortener/config"
+	"shortener/internal/adapters/repository"
+	"shortener/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const dayLayout = "2006-01-02"
+
+const pageSize = 1000
+
+type SqliteRepository struct {
+	db *sql.DB
+}
+
+func NewRepository(ctx context.Context, config config.Sqlite) (*SqliteRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, err
+	}
+
+	dsn := fmt.Sprintf(
+		"file:%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)&_txlock=immediate",
+		url.PathEscape(config.Path),
+		config.BusyTimeout.Milliseconds(),
+	)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(1)
+
+	if err = db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	if err = migrate(ctx, db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &SqliteRepository{
+		db: db,
+	}, nil
+}
+
+func (r *SqliteRepository) Save(ctx context.Context, link domain.Link) error {
+	query := `
+	insert into urls(original, shortened, created_at, expires_at, password_hash)
+	values (?, ?, ?, ?, nullif(?, ''))
+`
+	_, err := r.db.ExecContext(ctx, query, link.Original, link.Shortened, createdAt(link), expiresAt(link), link.PasswordHash)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExist
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (r *SqliteRepository) SaveBatch(ctx context.Context, links []domain.Link) ([]error, error) {
+	query := `
+	insert into urls(original, shortened, created_at, expires_at)
+	values (?, ?, ?, ?)
+	on conflict do nothing
+`
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	errs := make([]error, len(links))
+	for i, link := range links {
+		res, err := stmt.ExecContext(ctx, link.Original, link.Shortened, createdAt(link), expiresAt(link))
+		if err != nil {
+			return nil, err
+		}
+
+		if n, err := res.RowsAffected(); err != nil {
+			return nil, err
+		} else if n == 0 {
+			errs[i] = domain.ErrAlreadyExist
+		}
+	}
+
+	return errs, tx.Commit()
+}
+
+func (r *SqliteRepository) GetByShortened(ctx context.Context, shortened string) (domain.Link, error) {
+	query := `
+	select original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where shortened = ?
+`
+
+	return r.getLink(ctx, query, shortened)
+}
+
+func (r *SqliteRepository) GetByOriginal(ctx context.Context, origin string) (domain.Link, error) {
+	query := `
+	select original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where original = ? and password_hash is null and expires_at is null
+`
+
+	return r.getLink(ctx, query, origin)
+}
+
+func (r *SqliteRepository) getLink(ctx context.Context, query string, arg string) (domain.Link, error) {
+	link := domain.Link{}
+	expiresAt := sql.NullTime{}
+
+	err := r.db.QueryRowContext(ctx, query, arg).Scan(&link.Original, &link.Shortened, &link.CreatedAt, &expiresAt, &link.PasswordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.Link{}, domain.ErrNotFound
+		}
+
+		return domain.Link{}, err
+	}
+
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+
+	return link, nil
+}
+
+func (r *SqliteRepository) Delete(ctx context.Context, shortened string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err = tx.ExecContext(ctx, `delete from clicks where shortened = ?`, shortened); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `delete from urls where shortened = ?`, shortened)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return tx.Commit()
+}
+
+func (r *SqliteRepository) UpdateOriginal(ctx context.Context, shortened, original string) error {
+	query := `update urls set original = ? where shortened = ?`
+
+	res, err := r.db.ExecContext(ctx, query, original, shortened)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExist
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *SqliteRepository) Replace(ctx context.Context, link domain.Link) error {
+	query := `
+	update urls
+	set original = ?, created_at = ?, expires_at = ?, password_hash = nullif(?, '')
+	where shortened = ?
+`
+	res, err := r.db.ExecContext(ctx, query, link.Original, createdAt(link), expiresAt(link), link.PasswordHash, link.Shortened)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExist
+		}
+
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *SqliteRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	before = before.UTC()
+
+	query := `
+	delete from clicks
+	where shortened in (select shortened from urls where expires_at <= ?)
+`
+	if _, err = tx.ExecContext(ctx, query, before); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, `delete from urls where expires_at <= ?`, before)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+func (r *SqliteRepository) SaveClicks(ctx context.Context, clicks []domain.Click) error {
+	query := `
+	insert into clicks(shortened, clicked_at, referrer, user_agent, ip)
+	values (?, ?, ?, ?, ?)
+`
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, c := range clicks {
+		if _, err = stmt.ExecContext(ctx, c.Shortened, c.At.UTC(), c.Referrer, c.UserAgent, c.IP); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *SqliteRepository) GetClickStats(ctx context.Context, shortened string) (domain.ClickStats, error) {
+	query := `
+	select substr(clicked_at, 1, 10) as day, count(*)
+	from clicks
+	where shortened = ?
+	group by day
+	order by day
+`
+	rows, err := r.db.QueryContext(ctx, query, shortened)
+	if err != nil {
+		return domain.ClickStats{}, err
+	}
+	defer rows.Close()
+
+	stats := domain.ClickStats{Daily: []domain.DailyClicks{}}
+	for rows.Next() {
+		var day string
+		daily := domain.DailyClicks{}
+		if err := rows.Scan(&day, &daily.Count); err != nil {
+			return domain.ClickStats{}, err
+		}
+
+		daily.Day, err = time.Parse(dayLayout, day)
+		if err != nil {
+			return domain.ClickStats{}, err
+		}
+
+		stats.Total += daily.Count
+		stats.Daily = append(stats.Daily, daily)
+	}
+
+	return stats, rows.Err()
+}
+
+func (r *SqliteRepository) GetAPIKey(ctx context.Context, hash string) (domain.APIKey, error) {
+	query := `select name, key_hash from api_keys where key_hash = ? and revoked_at is null`
+
+	key := domain.APIKey{}
+	err := r.db.QueryRowContext(ctx, query, hash).Scan(&key.Name, &key.Hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.APIKey{}, domain.ErrNotFound
+		}
+
+		return domain.APIKey{}, err
+	}
+
+	return key, nil
+}
+
+func (r *SqliteRepository) NextBlock(ctx context.Context) (uint64, uint64, error) {
+	query := `
+	update short_code_seq
+	set next_id = next_id + increment_by
+	returning next_id - increment_by, increment_by
+`
+	var start, size int64
+	if err := r.db.QueryRowContext(ctx, query).Scan(&start, &size); err != nil {
+		return 0, 0, err
+	}
+
+	return uint64(start), uint64(size), nil
+}
+
+// ShortenedColumnLen reports no limit: SQLite does not enforce varchar lengths.
+func (r *SqliteRepository) ShortenedColumnLen(_ context.Context) (int, error) {
+	return 0, nil
+}
+
+func (r *SqliteRepository) WidenShortenedColumn(_ context.Context, _ int) error {
+	return nil
+}
+
+func (r *SqliteRepository) GetSetting(ctx context.Context, key string) (string, error) {
+	query := `select value from settings where key = ?`
+
+	var value string
+	err := r.db.QueryRowContext(ctx, query, key).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", domain.ErrNotFound
+		}
+
+		return "", err
+	}
+
+	return value, nil
+}
+
+func (r *SqliteRepository) SetSetting(ctx context.Context, key, value string) error {
+	query := `
+	insert into settings(key, value)
+	values (?, ?)
+	on conflict (key) do update set value = excluded.value
+`
+	_, err := r.db.ExecContext(ctx, query, key, value)
+
+	return err
+}
+
+func (r *SqliteRepository) ForEachShortened(ctx context.Context, fn func(shortened string) error) error {
+	rows, err := r.db.QueryContext(ctx, `select shortened from urls`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var shortened string
+		if err := rows.Scan(&shortened); err != nil {
+			return err
+		}
+
+		if err := fn(shortened); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r *SqliteRepository) ForEachLink(ctx context.Context, fn func(link domain.Link) error) error {
+	query := `
+	select id, original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where id > ?
+	order by id
+	limit ?
+`
+	var lastID int64
+	for {
+		links, err := r.linksPage(ctx, query, &lastID)
+		if err != nil {
+			return err
+		}
+
+		for _, link := range links {
+			if err := fn(link); err != nil {
+				return err
+			}
+		}
+
+		if len(links) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (r *SqliteRepository) linksPage(ctx context.Context, query string, lastID *int64) ([]domain.Link, error) {
+	rows, err := r.db.QueryContext(ctx, query, *lastID, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := make([]domain.Link, 0, pageSize)
+	for rows.Next() {
+		link := domain.Link{}
+		expiresAt := sql.NullTime{}
+
+		if err := rows.Scan(lastID, &link.Original, &link.Shortened, &link.CreatedAt, &expiresAt, &link.PasswordHash); err != nil {
+			return nil, err
+		}
+
+		if expiresAt.Valid {
+			link.ExpiresAt = &expiresAt.Time
+		}
+
+		links = append(links, link)
+	}
+
+	return links, rows.Err()
+}
+
+// List pages through links by (created_at, id). Domain matching is only
+// narrowed down in SQL and finished by filter.Match.
+func (r *SqliteRepository) List(ctx context.Context, filter domain.ListFilter) (domain.LinkPage, error) {
+	var (
+		after   any
+		afterID int64
+	)
+	if filter.Cursor != "" {
+		createdAt, key, err := repository.DecodeCursor(filter.Cursor)
+		if err != nil {
+			return domain.LinkPage{}, err
+		}
+
+		if afterID, err = strconv.ParseInt(key, 10, 64); err != nil {
+			return domain.LinkPage{}, domain.ErrInvalidCursor
+		}
+		after = createdAt
+	}
+
+	cmp, direction := ">", "asc"
+	if filter.Desc {
+		cmp, direction = "<", "desc"
+	}
+
+	query := fmt.Sprintf(`
+	select id, original, shortened, created_at, expires_at, coalesce(password_hash, '')
+	from urls
+	where (?1 is null or (created_at, id) %[1]s (?1, ?2))
+		and (?3 is null or created_at >= ?3)
+		and (?4 is null or created_at < ?4)
+		and (?5 = '' or instr(lower(original), ?5) > 0 or instr(lower(shortened), ?5) > 0)
+		and (?6 = '' or instr(lower(original), ?6) > 0)
+	order by created_at %[2]s, id %[2]s
+	limit ?7
+`, cmp, direction)
+
+	page := domain.LinkPage{Links: make([]domain.Link, 0, filter.Limit)}
+	var lastID int64
+	for {
+		rows, err := r.db.QueryContext(ctx, query, after, afterID, timeArg(filter.CreatedFrom), timeArg(filter.CreatedBefore),
+			strings.ToLower(filter.Query), strings.ToLower(filter.Domain), filter.Limit+1)
+		if err != nil {
+			return domain.LinkPage{}, err
+		}
+
+		scanned := 0
+		for rows.Next() {
+			var id int64
+			link := domain.Link{}
+			expiresAt := sql.NullTime{}
+			if err := rows.Scan(&id, &link.Original, &link.Shortened, &link.CreatedAt, &expiresAt, &link.PasswordHash); err != nil {
+				rows.Close()
+				return domain.LinkPage{}, err
+			}
+
+			if expiresAt.Valid {
+				link.ExpiresAt = &expiresAt.Time
+			}
+
+			scanned++
+			after, afterID = link.CreatedAt, id
+
+			if !filter.Match(link) {
+				continue
+			}
+
+			if len(page.Links) == filter.Limit {
+				rows.Close()
+				last := page.Links[len(page.Links)-1]
+				page.NextCursor = repository.EncodeCursor(last.CreatedAt, strconv.FormatInt(lastID, 10))
+				return page, nil
+			}
+
+			page.Links = append(page.Links, link)
+			lastID = id
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return domain.LinkPage{}, err
+		}
+
+		if scanned <= filter.Limit {
+			return page, nil
+		}
+	}
+}
+
+func (r *SqliteRepository) Close() {
+	_ = r.db.Close()
+}
+
+func createdAt(link domain.Link) time.Time {
+	if link.CreatedAt.IsZero() {
+		return time.Now().UTC()
+	}
+
+	return link.CreatedAt.UTC()
+}
+
+func expiresAt(link domain.Link) any {
+	if link.ExpiresAt == nil {
+		return nil
+	}
+
+	return link.ExpiresAt.UTC()
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+
+	code := sqliteErr.Code()
+
+	return code == sqlite3.SQLITE_CONSTRAINT_UNIQUE || code == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+}
+
+func timeArg(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+
+	return t.UTC()
+}
@@ -0,0 +1,267 @@
+package sqlite_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shortener/config"
+	"shortener/internal/adapters/repository/sqlite"
+	"shortener/internal/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRepository(t *testing.T) *sqlite.SqliteRepository {
+	t.Helper()
+
+	r, err := sqlite.NewRepository(context.Background(), config.Sqlite{
+		Path:        filepath.Join(t.TempDir(), "shortener.db"),
+		BusyTimeout: time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+func TestSaveAndGet(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+	future := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa", ExpiresAt: &future}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "bbb"}))
+
+	assert.ErrorIs(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "ccc"}), domain.ErrAlreadyExist)
+	assert.ErrorIs(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "aaa"}), domain.ErrAlreadyExist)
+
+	link, err := r.GetByShortened(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://a.com", link.Original)
+	assert.False(t, link.CreatedAt.IsZero())
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, future.Equal(*link.ExpiresAt))
+
+	link, err = r.GetByOriginal(ctx, "https://a.com")
+	require.NoError(t, err)
+	assert.Equal(t, "bbb", link.Shortened)
+
+	_, err = r.GetByShortened(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestProtectedLinks(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "bbb", PasswordHash: "hash"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "ccc", PasswordHash: "hash"}))
+
+	link, err := r.GetByShortened(ctx, "bbb")
+	require.NoError(t, err)
+	assert.Equal(t, "hash", link.PasswordHash)
+
+	link, err = r.GetByOriginal(ctx, "https://a.com")
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", link.Shortened)
+	assert.Empty(t, link.PasswordHash)
+
+	require.NoError(t, r.Delete(ctx, "aaa"))
+	_, err = r.GetByOriginal(ctx, "https://a.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestSaveBatch(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+
+	errs, err := r.SaveBatch(ctx, []domain.Link{
+		{Original: "https://a.com", Shortened: "xxx"},
+		{Original: "https://b.com", Shortened: "bbb"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []error{domain.ErrAlreadyExist, nil}, errs)
+
+	_, err = r.GetByShortened(ctx, "bbb")
+	assert.NoError(t, err)
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa"}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb"}))
+
+	assert.ErrorIs(t, r.UpdateOriginal(ctx, "aaa", "https://b.com"), domain.ErrAlreadyExist)
+	assert.ErrorIs(t, r.UpdateOriginal(ctx, "missing", "https://c.com"), domain.ErrNotFound)
+	require.NoError(t, r.UpdateOriginal(ctx, "aaa", "https://c.com"))
+
+	link, err := r.GetByShortened(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", link.Original)
+
+	require.NoError(t, r.Delete(ctx, "aaa"))
+	assert.ErrorIs(t, r.Delete(ctx, "aaa"), domain.ErrNotFound)
+}
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	createdAt := time.Date(2025, 5, 6, 7, 8, 9, 0, time.UTC)
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa", CreatedAt: createdAt}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb"}))
+
+	assert.ErrorIs(t, r.Replace(ctx, domain.Link{Original: "https://b.com", Shortened: "aaa"}), domain.ErrAlreadyExist)
+	assert.ErrorIs(t, r.Replace(ctx, domain.Link{Original: "https://c.com", Shortened: "missing"}), domain.ErrNotFound)
+
+	expiresAt := createdAt.Add(time.Hour)
+	require.NoError(t, r.Replace(ctx, domain.Link{
+		Original:     "https://c.com",
+		Shortened:    "aaa",
+		CreatedAt:    createdAt,
+		ExpiresAt:    &expiresAt,
+		PasswordHash: "hash",
+	}))
+
+	link, err := r.GetByShortened(ctx, "aaa")
+	require.NoError(t, err)
+	assert.Equal(t, "https://c.com", link.Original)
+	assert.True(t, createdAt.Equal(link.CreatedAt))
+	require.NotNil(t, link.ExpiresAt)
+	assert.True(t, expiresAt.Equal(*link.ExpiresAt))
+	assert.True(t, link.Protected())
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	originals := []string{
+		"https://example.com/a",
+		"https://docs.example.com/b",
+		"https://other.org/example",
+		"https://notexample.com/c",
+		"https://example.com/d",
+	}
+	for i, original := range originals {
+		require.NoError(t, r.Save(ctx, domain.Link{
+			Original:  original,
+			Shortened: fmt.Sprintf("code%d", i),
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	codes := func(page domain.LinkPage) []string {
+		out := make([]string, 0, len(page.Links))
+		for _, link := range page.Links {
+			out = append(out, link.Shortened)
+		}
+
+		return out
+	}
+
+	page, err := r.List(ctx, domain.ListFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code0", "code1"}, codes(page))
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code2", "code3"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code4"}, codes(page))
+	assert.Empty(t, page.NextCursor)
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 3, Desc: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code4", "code3", "code2"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 1, Desc: true, Domain: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code4"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 1, Desc: true, Domain: "example.com", Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code1"}, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 10, Query: "EXAMPLE/"})
+	require.NoError(t, err)
+	assert.Empty(t, codes(page))
+
+	page, err = r.List(ctx, domain.ListFilter{Limit: 10, Query: "Example"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code0", "code1", "code2", "code3", "code4"}, codes(page))
+
+	from, before := base.Add(time.Hour), base.Add(3*time.Hour)
+	page, err = r.List(ctx, domain.ListFilter{Limit: 10, CreatedFrom: &from, CreatedBefore: &before})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"code1", "code2"}, codes(page))
+
+	_, err = r.List(ctx, domain.ListFilter{Limit: 10, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+}
+
+func TestDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Minute)
+
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://a.com", Shortened: "aaa", ExpiresAt: &past}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://b.com", Shortened: "bbb", ExpiresAt: &future}))
+	require.NoError(t, r.Save(ctx, domain.Link{Original: "https://c.com", Shortened: "ccc"}))
+
+	deleted, err := r.DeleteExpired(ctx, time.Now())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, deleted)
+
+	_, err = r.GetByShortened(ctx, "aaa")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestClickStats(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+	day := time.Date(2026, 3, 1, 23, 30, 0, 0, time.UTC)
+
+	require.NoError(t, r.SaveClicks(ctx, []domain.Click{
+		{Shortened: "aaa", At: day},
+		{Shortened: "aaa", At: day.Add(time.Hour)},
+		{Shortened: "aaa", At: day.Add(2 * time.Hour)},
+		{Shortened: "bbb", At: day},
+	}))
+
+	stats, err := r.GetClickStats(ctx, "aaa")
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, stats.Total)
+	assert.Equal(t, []domain.DailyClicks{
+		{Day: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), Count: 1},
+		{Day: time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC), Count: 2},
+	}, stats.Daily)
+}
+
+func TestNextBlock(t *testing.T) {
+	ctx := context.Background()
+	r := newRepository(t)
+
+	start, size, err := r.NextBlock(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 0, start)
+	assert.EqualValues(t, 1000, size)
+
+	start, _, err = r.NextBlock(ctx)
+	require.NoError(t, err)
+	assert.EqualValues(t, 1000, start)
+}
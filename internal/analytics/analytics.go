@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+)
+
+const flushTimeout = 5 * time.Second
+
+type Store interface {
+	SaveClicks(ctx context.Context, clicks []domain.Click) error
+}
+
+type Options struct {
+	BatchSize     int
+	QueueSize     int
+	FlushInterval time.Duration
+}
+
+type Recorder struct {
+	store         Store
+	log           logger.Logger
+	queue         chan domain.Click
+	batchSize     int
+	flushInterval time.Duration
+	dropped       atomic.Int64
+
+	// mu guards closed, Record holds it for reading so that Close never
+	// closes the queue under a sender
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewRecorder(store Store, log logger.Logger, options Options) *Recorder {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+
+	if options.QueueSize < options.BatchSize {
+		options.QueueSize = options.BatchSize
+	}
+
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = time.Second
+	}
+
+	return &Recorder{
+		store:         store,
+		log:           log,
+		queue:         make(chan domain.Click, options.QueueSize),
+		batchSize:     options.BatchSize,
+		flushInterval: options.FlushInterval,
+	}
+}
+
+func (r *Recorder) Record(click domain.Click) {
+	if click.At.IsZero() {
+		click.At = time.Now().UTC()
+	}
+
+	click.IP = AnonymizeIP(click.IP)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.closed {
+		r.dropped.Add(1)
+		return
+	}
+
+	select {
+	case r.queue <- click:
+	default:
+		r.dropped.Add(1)
+	}
+}
+
+func (r *Recorder) Dropped() int64 {
+	return r.dropped.Load()
+}
+
+// Close stops accepting clicks. Run saves the ones already queued and returns,
+// so call it once the servers have finished their requests.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.queue)
+	}
+}
+
+// Run saves queued clicks in batches until Close is called and the queue is
+// drained.
+func (r *Recorder) Run() {
+	ticker := time.NewTicker(r.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]domain.Click, 0, r.batchSize)
+
+	for {
+		select {
+		case click, ok := <-r.queue:
+			if !ok {
+				r.flush(batch)
+				return
+			}
+
+			batch = append(batch, click)
+			if len(batch) >= r.batchSize {
+				batch = r.flush(batch)
+			}
+		case <-ticker.C:
+			batch = r.flush(batch)
+		}
+	}
+}
+
+func (r *Recorder) flush(batch []domain.Click) []domain.Click {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	if err := r.store.SaveClicks(ctx, batch); err != nil {
+		r.log.Error("save clicks failed",
+			logger.Field{Key: "count", Value: len(batch)},
+			logger.Field{Key: "error", Value: err})
+	}
+
+	return batch[:0]
+}
+
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
@@ -0,0 +1,138 @@
+package analytics_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"shortener/internal/analytics"
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeStore struct {
+	mu      sync.Mutex
+	batches [][]domain.Click
+}
+
+func (s *fakeStore) SaveClicks(_ context.Context, clicks []domain.Click) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches = append(s.batches, append([]domain.Click(nil), clicks...))
+	return nil
+}
+
+func (s *fakeStore) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+
+	return n
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	tests := []struct {
+		name string
+		ip   string
+		want string
+	}{
+		{
+			name: "ipv4",
+			ip:   "192.168.10.77",
+			want: "192.168.10.0",
+		},
+		{
+			name: "ipv6",
+			ip:   "2001:db8:abcd:12::1",
+			want: "2001:db8:abcd::",
+		},
+		{
+			name: "ipv4 mapped",
+			ip:   "::ffff:10.1.2.3",
+			want: "10.1.2.0",
+		},
+		{
+			name: "invalid",
+			ip:   "not an ip",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, analytics.AnonymizeIP(tt.ip))
+		})
+	}
+}
+
+func TestRecorderBatches(t *testing.T) {
+	store := &fakeStore{}
+	rec := analytics.NewRecorder(store, logger.NewNop(), analytics.Options{
+		BatchSize:     2,
+		QueueSize:     10,
+		FlushInterval: time.Hour,
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rec.Run()
+	}()
+
+	for range 5 {
+		rec.Record(domain.Click{Shortened: "ok", IP: "10.0.0.1"})
+	}
+
+	assert.Eventually(t, func() bool { return store.total() >= 4 }, time.Second, 10*time.Millisecond)
+
+	rec.Close()
+	<-done
+
+	assert.Equal(t, 5, store.total())
+	for _, batch := range store.batches {
+		assert.LessOrEqual(t, len(batch), 2)
+		for _, click := range batch {
+			assert.Equal(t, "10.0.0.0", click.IP)
+			assert.False(t, click.At.IsZero())
+		}
+	}
+}
+
+func TestRecorderDropsWhenFull(t *testing.T) {
+	rec := analytics.NewRecorder(&fakeStore{}, logger.NewNop(), analytics.Options{
+		BatchSize: 1,
+		QueueSize: 1,
+	})
+
+	rec.Record(domain.Click{Shortened: "ok"})
+	rec.Record(domain.Click{Shortened: "ok"})
+
+	assert.Equal(t, int64(1), rec.Dropped())
+}
+
+func TestRecorderClose(t *testing.T) {
+	store := &fakeStore{}
+	rec := analytics.NewRecorder(store, logger.NewNop(), analytics.Options{
+		BatchSize:     10,
+		QueueSize:     10,
+		FlushInterval: time.Hour,
+	})
+
+	rec.Record(domain.Click{Shortened: "ok"})
+	rec.Close()
+	rec.Close()
+	rec.Record(domain.Click{Shortened: "late"})
+
+	rec.Run()
+
+	assert.Equal(t, 1, store.total())
+	assert.Equal(t, int64(1), rec.Dropped())
+}
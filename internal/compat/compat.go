@@ -0,0 +1,148 @@
+package compat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+)
+
+const (
+	fingerprintKey = "generator"
+
+	maxExamples = 5
+)
+
+type Store interface {
+	ShortenedColumnLen(ctx context.Context) (int, error)
+	WidenShortenedColumn(ctx context.Context, size int) error
+	GetSetting(ctx context.Context, key string) (string, error)
+	SetSetting(ctx context.Context, key, value string) error
+	ForEachShortened(ctx context.Context, fn func(shortened string) error) error
+}
+
+type Validator interface {
+	ValidateShortened(shortened string) bool
+	ValidateAlias(alias string) bool
+}
+
+type Options struct {
+	Alphabet    string
+	Len         int
+	AutoMigrate bool
+	Protection  bool
+}
+
+// Check makes sure codes produced by the configured generator can be stored
+// and that codes already stored are still accepted by the validator.
+func Check(ctx context.Context, store Store, validator Validator, options Options, log logger.Logger) error {
+	if err := checkAlphabet(options.Alphabet); err != nil {
+		return err
+	}
+
+	if err := checkColumn(ctx, store, options, log); err != nil {
+		return err
+	}
+
+	fingerprint := strconv.Itoa(options.Len) + ":" + options.Alphabet
+
+	stored, err := store.GetSetting(ctx, fingerprintKey)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+
+	if stored == fingerprint {
+		return nil
+	}
+
+	if options.Protection {
+		if err = checkExisting(ctx, store, validator); err != nil {
+			return err
+		}
+	}
+
+	return store.SetSetting(ctx, fingerprintKey, fingerprint)
+}
+
+func checkAlphabet(alphabet string) error {
+	if len(alphabet) < 2 {
+		return errors.New("GENERATOR_ALPHABET must contain at least two characters")
+	}
+
+	seen := make(map[rune]struct{}, len(alphabet))
+	for _, r := range alphabet {
+		if !isUnreserved(r) {
+			return fmt.Errorf("GENERATOR_ALPHABET contains %q, only ASCII letters, digits and -._~ are safe in a URL path", r)
+		}
+
+		if _, ok := seen[r]; ok {
+			return fmt.Errorf("GENERATOR_ALPHABET contains %q more than once", r)
+		}
+
+		seen[r] = struct{}{}
+	}
+
+	return nil
+}
+
+func isUnreserved(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("-._~", r)
+}
+
+func checkColumn(ctx context.Context, store Store, options Options, log logger.Logger) error {
+	size, err := store.ShortenedColumnLen(ctx)
+	if err != nil {
+		return err
+	}
+
+	if size == 0 || options.Len <= size {
+		return nil
+	}
+
+	if !options.AutoMigrate {
+		return fmt.Errorf(
+			"GENERATOR_LEN=%d does not fit urls.shortened varchar(%d): lower GENERATOR_LEN or set DB_AUTO_MIGRATE=true to widen the column",
+			options.Len, size,
+		)
+	}
+
+	log.Info("widening shortened column",
+		logger.Field{Key: "from", Value: size},
+		logger.Field{Key: "to", Value: options.Len})
+
+	return store.WidenShortenedColumn(ctx, options.Len)
+}
+
+func checkExisting(ctx context.Context, store Store, validator Validator) error {
+	var invalid int
+	examples := make([]string, 0, maxExamples)
+
+	err := store.ForEachShortened(ctx, func(shortened string) error {
+		if validator.ValidateShortened(shortened) || validator.ValidateAlias(shortened) {
+			return nil
+		}
+
+		invalid++
+		if len(examples) < maxExamples {
+			examples = append(examples, shortened)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if invalid == 0 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%d stored codes (e.g. %s) do not match GENERATOR_ALPHABET and GENERATOR_LEN and would stop resolving: restore the previous generator settings or set SERVICE_PROTECTION=false",
+		invalid, strings.Join(examples, ", "),
+	)
+}
@@ -0,0 +1,140 @@
+package compat_test
+
+import (
+	"context"
+	"testing"
+
+	"shortener/internal/compat"
+	"shortener/internal/domain"
+	"shortener/internal/validator"
+	"shortener/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeStore struct {
+	columnLen int
+	widenedTo int
+	settings  map[string]string
+	codes     []string
+}
+
+func (s *fakeStore) ShortenedColumnLen(_ context.Context) (int, error) {
+	return s.columnLen, nil
+}
+
+func (s *fakeStore) WidenShortenedColumn(_ context.Context, size int) error {
+	s.widenedTo = size
+	return nil
+}
+
+func (s *fakeStore) GetSetting(_ context.Context, key string) (string, error) {
+	value, ok := s.settings[key]
+	if !ok {
+		return "", domain.ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (s *fakeStore) SetSetting(_ context.Context, key, value string) error {
+	s.settings[key] = value
+	return nil
+}
+
+func (s *fakeStore) ForEachShortened(_ context.Context, fn func(string) error) error {
+	for _, code := range s.codes {
+		if err := fn(code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name        string
+		store       *fakeStore
+		options     compat.Options
+		wantErr     string
+		wantWidened int
+	}{
+		{
+			name:    "ok",
+			store:   &fakeStore{columnLen: 32, codes: []string{"abcab", "spring-sale"}},
+			options: compat.Options{Alphabet: "abc", Len: 5, Protection: true},
+		},
+		{
+			name:    "unsafe alphabet",
+			store:   &fakeStore{},
+			options: compat.Options{Alphabet: "ab/", Len: 5},
+			wantErr: "GENERATOR_ALPHABET contains '/'",
+		},
+		{
+			name:    "non ascii alphabet",
+			store:   &fakeStore{},
+			options: compat.Options{Alphabet: "abя", Len: 5},
+			wantErr: "GENERATOR_ALPHABET contains 'я'",
+		},
+		{
+			name:    "duplicate letters",
+			store:   &fakeStore{},
+			options: compat.Options{Alphabet: "abca", Len: 5},
+			wantErr: "more than once",
+		},
+		{
+			name:    "column too short",
+			store:   &fakeStore{columnLen: 10},
+			options: compat.Options{Alphabet: "abc", Len: 12},
+			wantErr: "GENERATOR_LEN=12 does not fit urls.shortened varchar(10)",
+		},
+		{
+			name:        "column widened",
+			store:       &fakeStore{columnLen: 10},
+			options:     compat.Options{Alphabet: "abc", Len: 40, AutoMigrate: true},
+			wantWidened: 40,
+		},
+		{
+			name:    "stored codes invalid",
+			store:   &fakeStore{codes: []string{"abcab", "xyz!!", "ab"}},
+			options: compat.Options{Alphabet: "abc", Len: 5, Protection: true},
+			wantErr: "2 stored codes (e.g. xyz!!, ab)",
+		},
+		{
+			name:    "stored codes ignored without protection",
+			store:   &fakeStore{codes: []string{"xyz!!"}},
+			options: compat.Options{Alphabet: "abc", Len: 5},
+		},
+		{
+			name: "fingerprint unchanged",
+			store: &fakeStore{
+				codes:    []string{"xyz!!"},
+				settings: map[string]string{"generator": "5:abc"},
+			},
+			options: compat.Options{Alphabet: "abc", Len: 5, Protection: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.store.settings == nil {
+				tt.store.settings = make(map[string]string)
+			}
+
+			v, err := validator.NewValidator(tt.options.Alphabet, tt.options.Len)
+			require.NoError(t, err)
+
+			err = compat.Check(context.Background(), tt.store, v, tt.options, logger.NewNop())
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantWidened, tt.store.widenedTo)
+			assert.NotEmpty(t, tt.store.settings["generator"])
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package grpchandlers
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"shortener/internal/controllers/grpc/pb"
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type Usecase interface {
+	CreateShortened(ctx context.Context, params domain.CreateParams) (domain.Link, error)
+	GetOriginalByShortened(ctx context.Context, params domain.ResolveParams) (string, error)
+}
+
+type Handlers struct {
+	pb.UnimplementedShortenerServer
+
+	uc  Usecase
+	log logger.Logger
+}
+
+func NewHandlers(uc Usecase, log logger.Logger) *Handlers {
+	return &Handlers{
+		uc:  uc,
+		log: log,
+	}
+}
+
+func (h *Handlers) CreateShortened(ctx context.Context, req *pb.CreateShortenedRequest) (*pb.CreateShortenedResponse, error) {
+	ttl, err := domain.TTLFromSeconds(req.GetTtlSeconds())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid expiration")
+	}
+
+	params := domain.CreateParams{
+		URL:      req.GetUrl(),
+		Alias:    req.GetAlias(),
+		TTL:      ttl,
+		Password: req.GetPassword(),
+	}
+
+	if req.GetExpiresAt() != nil {
+		expiresAt := req.GetExpiresAt().AsTime()
+		params.ExpiresAt = &expiresAt
+	}
+
+	link, err := h.uc.CreateShortened(ctx, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidURL):
+			return nil, status.Error(codes.InvalidArgument, "invalid url")
+		case errors.Is(err, domain.ErrForbiddenDestination):
+			return nil, status.Error(codes.FailedPrecondition, "forbidden destination")
+		case errors.Is(err, domain.ErrInvalidAlias):
+			return nil, status.Error(codes.InvalidArgument, "invalid alias")
+		case errors.Is(err, domain.ErrInvalidExpiration):
+			return nil, status.Error(codes.InvalidArgument, "invalid expiration")
+		case errors.Is(err, domain.ErrInvalidPassword):
+			return nil, status.Error(codes.InvalidArgument, "invalid password")
+		case errors.Is(err, domain.ErrAlreadyExist):
+			return nil, status.Error(codes.AlreadyExists, "already exists")
+		}
+
+		h.log.Error("create shortened failed",
+			logger.Field{Key: "url", Value: req.GetUrl()},
+			logger.Field{Key: "alias", Value: req.GetAlias()},
+			logger.Field{Key: "error", Value: err})
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	resp := &pb.CreateShortenedResponse{Shortened: link.Shortened}
+	if link.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(*link.ExpiresAt)
+	}
+
+	return resp, nil
+}
+
+func (h *Handlers) GetOriginal(ctx context.Context, req *pb.GetOriginalRequest) (*pb.GetOriginalResponse, error) {
+	original, err := h.uc.GetOriginalByShortened(ctx, resolveParams(ctx, req.GetShortened()))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidShortened), errors.Is(err, domain.ErrNotFound):
+			return nil, status.Error(codes.NotFound, "not found")
+		case errors.Is(err, domain.ErrExpired):
+			return nil, status.Error(codes.FailedPrecondition, "expired")
+		case errors.Is(err, domain.ErrPasswordRequired):
+			return nil, status.Error(codes.Unauthenticated, "password required")
+		case errors.Is(err, domain.ErrInvalidPassword):
+			return nil, status.Error(codes.Unauthenticated, "invalid password")
+		case errors.Is(err, domain.ErrTooManyAttempts):
+			return nil, status.Error(codes.ResourceExhausted, "too many attempts")
+		}
+
+		h.log.Error("get original failed",
+			logger.Field{Key: "shortened", Value: req.GetShortened()},
+			logger.Field{Key: "error", Value: err})
+
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	return &pb.GetOriginalResponse{Original: original}, nil
+}
+
+func resolveParams(ctx context.Context, shortened string) domain.ResolveParams {
+	params := domain.ResolveParams{Shortened: shortened}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			params.UserAgent = ua[0]
+		}
+
+		if ref := md.Get("referer"); len(ref) > 0 {
+			params.Referrer = ref[0]
+		}
+
+		if password := md.Get("x-link-password"); len(password) > 0 {
+			params.Password = password[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		params.IP = hostOnly(p.Addr.String())
+	}
+
+	return params
+}
+
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
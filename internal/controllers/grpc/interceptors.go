@@ -0,0 +1,156 @@
+package grpchandlers
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"shortener/internal/controllers/grpc/pb"
+	"shortener/internal/domain"
+	"shortener/internal/ratelimit"
+	"shortener/pkg/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type KeyStore interface {
+	GetAPIKey(ctx context.Context, hash string) (domain.APIKey, error)
+}
+
+type InterceptorOptions struct {
+	Keys           KeyStore
+	AuthEnabled    bool
+	CreateLimiter  *ratelimit.Limiter
+	ResolveLimiter *ratelimit.Limiter
+}
+
+type Interceptors struct {
+	log         logger.Logger
+	keys        KeyStore
+	authEnabled bool
+	limiters    map[string]*ratelimit.Limiter
+}
+
+type apiKeyContextKey struct{}
+
+type apiKeyLookupContextKey struct{}
+
+type apiKeyLookup struct {
+	name string
+	err  error
+}
+
+var errNoAPIKey = errors.New("no api key")
+
+// writeMethods mirror the HTTP routes guarded by an API key.
+var writeMethods = map[string]bool{
+	pb.Shortener_CreateShortened_FullMethodName: true,
+}
+
+func NewInterceptors(log logger.Logger, options InterceptorOptions) *Interceptors {
+	return &Interceptors{
+		log:         log,
+		keys:        options.Keys,
+		authEnabled: options.AuthEnabled,
+		limiters: map[string]*ratelimit.Limiter{
+			pb.Shortener_CreateShortened_FullMethodName: options.CreateLimiter,
+			pb.Shortener_GetOriginal_FullMethodName:     options.ResolveLimiter,
+		},
+	}
+}
+
+// Limit shares the HTTP limits and, like the HTTP middleware, runs before the
+// API key check and is keyed by a valid API key or else by the peer IP.
+func (i *Interceptors) Limit() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		limiter := i.limiters[info.FullMethod]
+		if limiter == nil {
+			return handler(ctx, req)
+		}
+
+		key := ""
+		if i.authEnabled {
+			var lookup apiKeyLookup
+			ctx, lookup = i.lookupAPIKey(ctx)
+			if lookup.err == nil {
+				key = "key:" + lookup.name
+			}
+		}
+
+		if key == "" {
+			key = "ip:"
+			if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+				key += hostOnly(p.Addr.String())
+			}
+		}
+
+		res := limiter.Allow(key)
+		if !res.Allowed {
+			retryAfter := strconv.Itoa(int(math.Ceil(res.RetryAfter.Seconds())))
+			if err := grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter)); err != nil {
+				i.log.Debug("set retry-after header failed",
+					logger.Field{Key: "error", Value: err})
+			}
+
+			return nil, status.Error(codes.ResourceExhausted, "too many requests")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (i *Interceptors) RequireAPIKey() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !i.authEnabled || !writeMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		ctx, lookup := i.lookupAPIKey(ctx)
+		if lookup.err != nil {
+			if errors.Is(lookup.err, errNoAPIKey) {
+				return nil, status.Error(codes.Unauthenticated, "unauthorized")
+			}
+
+			if errors.Is(lookup.err, domain.ErrNotFound) {
+				return nil, status.Error(codes.PermissionDenied, "forbidden")
+			}
+
+			i.log.Error("api key lookup failed",
+				logger.Field{Key: "error", Value: lookup.err})
+
+			return nil, status.Error(codes.Internal, "internal error")
+		}
+
+		return handler(context.WithValue(ctx, apiKeyContextKey{}, lookup.name), req)
+	}
+}
+
+// lookupAPIKey resolves the bearer token once per call and keeps the result
+// in the context for the interceptors that follow.
+func (i *Interceptors) lookupAPIKey(ctx context.Context) (context.Context, apiKeyLookup) {
+	if cached, ok := ctx.Value(apiKeyLookupContextKey{}).(apiKeyLookup); ok {
+		return ctx, cached
+	}
+
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token, _ = strings.CutPrefix(values[0], "Bearer ")
+			token = strings.TrimSpace(token)
+		}
+	}
+
+	lookup := apiKeyLookup{err: errNoAPIKey}
+	if token != "" {
+		key, err := i.keys.GetAPIKey(ctx, domain.HashAPIKey(token))
+		lookup = apiKeyLookup{name: key.Name, err: err}
+	}
+
+	return context.WithValue(ctx, apiKeyLookupContextKey{}, lookup), lookup
+}
@@ -8,10 +8,17 @@ import (
 
 type Middleware interface {
 	SetRequestID() fiber.Handler
+	RequireAPIKey() fiber.Handler
+	RequireAdmin() fiber.Handler
+	LimitBody() fiber.Handler
+	LimitCreate() fiber.Handler
+	LimitCreateBatch() fiber.Handler
+	LimitResolve() fiber.Handler
 }
 
 type SuccessResponse[T any] struct {
-	Data T `json:"data"`
+	Data       T      `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type ErrorResponse struct {
@@ -25,6 +32,14 @@ func writeSuccess[T any](c *fiber.Ctx, status int, data T) error {
 	})
 }
 
+// writeList answers with one page of a list; next_cursor is omitted on the last page.
+func writeList[T any](c *fiber.Ctx, data []T, nextCursor string) error {
+	return c.Status(fiber.StatusOK).JSON(SuccessResponse[[]T]{
+		Data:       data,
+		NextCursor: nextCursor,
+	})
+}
+
 func writeError(c *fiber.Ctx, status int, msg string) error {
 	return c.Status(status).JSON(ErrorResponse{
 		Status: status,
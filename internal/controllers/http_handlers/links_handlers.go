@@ -0,0 +1,187 @@
+package httphandlers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type updateOriginalParams struct {
+	URL string `json:"url"`
+}
+
+type updateOriginalResponse struct {
+	Shortened string `json:"shortened"`
+	Original  string `json:"original"`
+}
+
+func (h *ApiHandlers) UpdateOriginal() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shortened := c.Params("shortened")
+
+		req := updateOriginalParams{}
+		if err := c.BodyParser(&req); err != nil {
+			return writeError(c, fiber.StatusBadRequest, "invalid json")
+		}
+
+		original, err := h.uc.UpdateOriginal(c.UserContext(), shortened, req.URL)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidShortened) || errors.Is(err, domain.ErrNotFound) {
+				return writeError(c, fiber.StatusNotFound, "not found")
+			}
+
+			if errors.Is(err, domain.ErrInvalidURL) {
+				return writeError(c, fiber.StatusBadRequest, "invalid url")
+			}
+
+			if errors.Is(err, domain.ErrForbiddenDestination) {
+				return writeError(c, fiber.StatusUnprocessableEntity, "forbidden destination")
+			}
+
+			if errors.Is(err, domain.ErrAlreadyExist) {
+				return writeError(c, fiber.StatusConflict, "already exists")
+			}
+
+			getLogger(c).Error("update original failed",
+				logger.Field{Key: "shortened", Value: shortened},
+				logger.Field{Key: "url", Value: req.URL},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		return writeSuccess(c, fiber.StatusOK, updateOriginalResponse{
+			Shortened: shortened,
+			Original:  original,
+		})
+	}
+}
+
+func (h *ApiHandlers) DeleteShortened() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shortened := c.Params("shortened")
+
+		err := h.uc.DeleteShortened(c.UserContext(), shortened)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidShortened) || errors.Is(err, domain.ErrNotFound) {
+				return writeError(c, fiber.StatusNotFound, "not found")
+			}
+
+			getLogger(c).Error("delete shortened failed",
+				logger.Field{Key: "shortened", Value: shortened},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+}
+
+type linkResponse struct {
+	Shortened string     `json:"shortened"`
+	ShortURL  string     `json:"short_url"`
+	Original  string     `json:"original,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Protected bool       `json:"protected"`
+}
+
+func (h *ApiHandlers) ListLinks() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		filter, err := listFilter(c)
+		if err != nil {
+			return writeError(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		page, err := h.uc.ListLinks(c.UserContext(), filter)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidFilter) || errors.Is(err, domain.ErrInvalidCursor) {
+				return writeError(c, fiber.StatusBadRequest, err.Error())
+			}
+
+			getLogger(c).Error("list links failed",
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		links := make([]linkResponse, 0, len(page.Links))
+		for _, link := range page.Links {
+			resp := linkResponse{
+				Shortened: link.Shortened,
+				ShortURL:  h.shortURL(c, link.Shortened),
+				CreatedAt: link.CreatedAt.UTC(),
+				ExpiresAt: link.ExpiresAt,
+				Protected: link.Protected(),
+			}
+
+			// the destination of a protected link is only revealed with its password
+			if !resp.Protected {
+				resp.Original = link.Original
+			}
+
+			links = append(links, resp)
+		}
+
+		return writeList(c, links, page.NextCursor)
+	}
+}
+
+func listFilter(c *fiber.Ctx) (domain.ListFilter, error) {
+	filter := domain.ListFilter{
+		Domain: c.Query("domain"),
+		Query:  c.Query("q"),
+		Cursor: c.Query("cursor"),
+	}
+
+	switch c.Query("sort", "-created_at") {
+	case "-created_at":
+		filter.Desc = true
+	case "created_at":
+	default:
+		return filter, errors.New("sort must be created_at or -created_at")
+	}
+
+	if raw := c.Query("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+
+		filter.Limit = limit
+	}
+
+	var err error
+	if filter.CreatedFrom, err = queryTime(c, "created_from"); err != nil {
+		return filter, err
+	}
+
+	if filter.CreatedBefore, err = queryTime(c, "created_before"); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+// queryTime accepts RFC 3339 timestamps and plain dates, the latter as UTC midnight.
+func queryTime(c *fiber.Ctx, key string) (*time.Time, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.DateOnly} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or a YYYY-MM-DD date", key)
+}
@@ -1,28 +1,287 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	httphandlers "shortener/internal/controllers/http_handlers"
+	"shortener/internal/domain"
+	"shortener/internal/ratelimit"
 	"shortener/pkg/logger"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+type KeyStore interface {
+	GetAPIKey(ctx context.Context, hash string) (domain.APIKey, error)
+}
+
+type RequestObserver interface {
+	ObserveRequest(route, method string, status int, duration time.Duration)
+}
+
+type Options struct {
+	Keys           KeyStore
+	Observer       RequestObserver
+	AuthEnabled    bool
+	CreateLimiter  *ratelimit.Limiter
+	ResolveLimiter *ratelimit.Limiter
+}
+
 type Middleware struct {
-	log logger.Logger
+	log            logger.Logger
+	keys           KeyStore
+	observer       RequestObserver
+	authEnabled    bool
+	createLimiter  *ratelimit.Limiter
+	resolveLimiter *ratelimit.Limiter
 }
 
-func NewMiddleware(log logger.Logger) *Middleware {
+func NewMiddleware(log logger.Logger, options Options) *Middleware {
 	return &Middleware{
-		log: log,
+		log:            log,
+		keys:           options.Keys,
+		observer:       options.Observer,
+		authEnabled:    options.AuthEnabled,
+		createLimiter:  options.CreateLimiter,
+		resolveLimiter: options.ResolveLimiter,
 	}
 }
 
 func (mw *Middleware) SetRequestID() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		logWithReq := mw.log.With(logger.Field{Key: "request_id", Value: uuid.New().String()})
+		requestID := uuid.New().String()
+		logWithReq := mw.log.With(logger.Field{Key: "request_id", Value: requestID})
+
+		span := trace.SpanFromContext(c.UserContext())
+		if span.SpanContext().IsValid() {
+			span.SetAttributes(attribute.String("request.id", requestID))
+			logWithReq = logWithReq.With(logger.Field{Key: "trace_id", Value: span.SpanContext().TraceID().String()})
+		}
 
 		c.Locals("logger", logWithReq)
 
 		return c.Next()
 	}
 }
+
+func (mw *Middleware) Observe() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if mw.observer == nil {
+			return c.Next()
+		}
+
+		start := time.Now()
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = errorStatus(err)
+		}
+
+		mw.observer.ObserveRequest(c.Route().Path, strings.Clone(c.Method()), status, time.Since(start))
+
+		return err
+	}
+}
+
+func (mw *Middleware) RequireAPIKey() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !mw.authEnabled {
+			return c.Next()
+		}
+
+		return mw.checkAPIKey(c)
+	}
+}
+
+// RequireAdmin fails closed: without AUTH_ENABLED there is no way to tell an
+// operator from anyone else, so admin routes are refused outright.
+func (mw *Middleware) RequireAdmin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !mw.authEnabled {
+			return writeError(c, fiber.StatusForbidden, "admin endpoints require AUTH_ENABLED=true")
+		}
+
+		return mw.checkAPIKey(c)
+	}
+}
+
+func (mw *Middleware) checkAPIKey(c *fiber.Ctx) error {
+	name, err := mw.lookupAPIKey(c)
+	if err != nil {
+		if errors.Is(err, errNoAPIKey) {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="shortener"`)
+			return writeError(c, fiber.StatusUnauthorized, "unauthorized")
+		}
+
+		if errors.Is(err, domain.ErrNotFound) {
+			return writeError(c, fiber.StatusForbidden, "forbidden")
+		}
+
+		mw.log.Error("api key lookup failed",
+			logger.Field{Key: "error", Value: err})
+
+		return writeError(c, fiber.StatusInternalServerError, "internal error")
+	}
+
+	c.Locals("api_key", name)
+
+	return c.Next()
+}
+
+var errNoAPIKey = errors.New("no api key")
+
+type apiKeyLookup struct {
+	name string
+	err  error
+}
+
+// lookupAPIKey resolves the bearer token once per request, the rate limiter
+// and the auth check share the result.
+func (mw *Middleware) lookupAPIKey(c *fiber.Ctx) (string, error) {
+	if cached, ok := c.Locals("api_key_lookup").(apiKeyLookup); ok {
+		return cached.name, cached.err
+	}
+
+	lookup := apiKeyLookup{err: errNoAPIKey}
+
+	token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	token = strings.TrimSpace(token)
+	if ok && token != "" {
+		key, err := mw.keys.GetAPIKey(c.UserContext(), domain.HashAPIKey(token))
+		lookup = apiKeyLookup{name: key.Name, err: err}
+	}
+
+	c.Locals("api_key_lookup", lookup)
+
+	return lookup.name, lookup.err
+}
+
+// LimitBody buffers the request body up to fiber.DefaultBodyLimit. The server
+// streams request bodies for the import endpoint, which also lifts fasthttp's
+// own limit, so every other route must pass through here before reading one.
+func (mw *Middleware) LimitBody() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Request().Header.ContentLength() > fiber.DefaultBodyLimit {
+			return writeError(c, fiber.StatusRequestEntityTooLarge, "request body too large")
+		}
+
+		stream := c.Context().RequestBodyStream()
+		if stream == nil {
+			return c.Next()
+		}
+
+		body, err := io.ReadAll(io.LimitReader(stream, fiber.DefaultBodyLimit+1))
+		if err != nil {
+			return writeError(c, fiber.StatusBadRequest, "read request body failed")
+		}
+
+		if len(body) > fiber.DefaultBodyLimit {
+			return writeError(c, fiber.StatusRequestEntityTooLarge, "request body too large")
+		}
+
+		c.Request().SetBodyRaw(body)
+
+		return c.Next()
+	}
+}
+
+func (mw *Middleware) LimitCreate() fiber.Handler {
+	return mw.rateLimit(mw.createLimiter, singleCost)
+}
+
+// LimitCreateBatch charges one create token per URL in the batch.
+func (mw *Middleware) LimitCreateBatch() fiber.Handler {
+	return mw.rateLimit(mw.createLimiter, batchCost)
+}
+
+func (mw *Middleware) LimitResolve() fiber.Handler {
+	return mw.rateLimit(mw.resolveLimiter, singleCost)
+}
+
+func singleCost(*fiber.Ctx) int {
+	return 1
+}
+
+// batchCost counts the URLs of a batch request, a body that does not parse
+// costs one token and is rejected by the handler.
+func batchCost(c *fiber.Ctx) int {
+	var req struct {
+		URLs []json.RawMessage `json:"urls"`
+	}
+
+	if err := json.Unmarshal(c.Body(), &req); err != nil || len(req.URLs) == 0 {
+		return 1
+	}
+
+	return len(req.URLs)
+}
+
+// rateLimit runs before authentication so that requests with bad keys are
+// throttled too. A valid API key gets its own bucket, anything else is
+// charged to the client IP, so made-up tokens cannot buy fresh buckets.
+func (mw *Middleware) rateLimit(limiter *ratelimit.Limiter, cost func(c *fiber.Ctx) int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if limiter == nil {
+			return c.Next()
+		}
+
+		n := cost(c)
+		if n > limiter.Burst() {
+			return writeError(c, fiber.StatusRequestEntityTooLarge, "request exceeds rate limit burst")
+		}
+
+		res := limiter.AllowN(mw.limitKey(c), n)
+
+		c.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+		c.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		c.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset.Seconds())))
+
+		if !res.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(res.RetryAfter.Seconds())))
+			return writeError(c, fiber.StatusTooManyRequests, "too many requests")
+		}
+
+		return c.Next()
+	}
+}
+
+func (mw *Middleware) limitKey(c *fiber.Ctx) string {
+	if mw.authEnabled {
+		if name, err := mw.lookupAPIKey(c); err == nil {
+			return "key:" + name
+		}
+	}
+
+	return "ip:" + c.IP()
+}
+
+func errorStatus(err error) int {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
+	}
+
+	return fiber.StatusInternalServerError
+}
+
+func ceilSeconds(seconds float64) int {
+	return int(math.Ceil(seconds))
+}
+
+func writeError(c *fiber.Ctx, status int, msg string) error {
+	return c.Status(status).JSON(httphandlers.ErrorResponse{
+		Status: status,
+		Msg:    msg,
+	})
+}
@@ -0,0 +1,113 @@
+package httphandlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"shortener/internal/domain"
+	"shortener/internal/qr"
+	"shortener/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func (h *ApiHandlers) GetQR() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shortened := c.Params("shortened")
+
+		options, err := qrOptions(c)
+		if err != nil {
+			return writeError(c, fiber.StatusBadRequest, err.Error())
+		}
+
+		link, err := h.uc.GetLink(c.UserContext(), shortened)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidShortened) || errors.Is(err, domain.ErrNotFound) {
+				return writeError(c, fiber.StatusNotFound, "not found")
+			}
+
+			if errors.Is(err, domain.ErrExpired) {
+				return writeError(c, fiber.StatusGone, "expired")
+			}
+
+			getLogger(c).Error("get link failed",
+				logger.Field{Key: "shortened", Value: shortened},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		buf := bytes.Buffer{}
+		if err = qr.Write(&buf, h.shortURL(c, link.Shortened), options); err != nil {
+			if errors.Is(err, qr.ErrInvalidOptions) {
+				return writeError(c, fiber.StatusBadRequest, err.Error())
+			}
+
+			getLogger(c).Error("qr rendering failed",
+				logger.Field{Key: "shortened", Value: shortened},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		c.Set(fiber.HeaderContentType, options.ContentType())
+		c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+
+		return c.Status(fiber.StatusOK).Send(buf.Bytes())
+	}
+}
+
+func qrOptions(c *fiber.Ctx) (qr.Options, error) {
+	options := qr.DefaultOptions()
+
+	options.Format = strings.ToLower(c.Query("format", options.Format))
+	options.Level = strings.ToUpper(c.Query("level", options.Level))
+
+	var err error
+	if options.Size, err = queryInt(c, "size", options.Size); err != nil {
+		return qr.Options{}, err
+	}
+
+	if options.Margin, err = queryInt(c, "margin", options.Margin); err != nil {
+		return qr.Options{}, err
+	}
+
+	if fg := c.Query("fg"); fg != "" {
+		if options.Foreground, err = qr.ParseColor(fg); err != nil {
+			return qr.Options{}, err
+		}
+	}
+
+	if bg := c.Query("bg"); bg != "" {
+		if options.Background, err = qr.ParseColor(bg); err != nil {
+			return qr.Options{}, err
+		}
+	}
+
+	return options, options.Validate()
+}
+
+func queryInt(c *fiber.Ctx, key string, def int) (int, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return def, nil
+	}
+
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s must be an integer", qr.ErrInvalidOptions, key)
+	}
+
+	return v, nil
+}
+
+func (h *ApiHandlers) shortURL(c *fiber.Ctx, shortened string) string {
+	if h.publicURL != "" {
+		return h.publicURL + "/" + shortened
+	}
+
+	return c.BaseURL() + "/" + shortened
+}
@@ -0,0 +1,117 @@
+package httphandlers
+
+import (
+	"errors"
+	"fmt"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const notFoundPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Link not found</title>
+</head>
+<body>
+<h1>404</h1>
+<p>This short link does not exist.</p>
+</body>
+</html>`
+
+const gonePage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Link expired</title>
+</head>
+<body>
+<h1>410</h1>
+<p>This short link has expired.</p>
+</body>
+</html>`
+
+const passwordPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Password required</title>
+</head>
+<body>
+<h1>Password required</h1>
+%s<form method="post">
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>`
+
+const tooManyAttemptsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Too many attempts</title>
+</head>
+<body>
+<h1>429</h1>
+<p>Too many wrong passwords for this link, try again later.</p>
+</body>
+</html>`
+
+func (h *ApiHandlers) Redirect() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return h.redirect(c, resolveParams(c, c.Params("shortened")), h.redirectStatus)
+	}
+}
+
+// RedirectWithPassword handles the password form served for protected links.
+func (h *ApiHandlers) RedirectWithPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		params := resolveParams(c, c.Params("shortened"))
+		params.Password = c.FormValue("password")
+
+		return h.redirect(c, params, fiber.StatusSeeOther)
+	}
+}
+
+func (h *ApiHandlers) redirect(c *fiber.Ctx, params domain.ResolveParams, status int) error {
+	original, err := h.uc.GetOriginalByShortened(c.UserContext(), params)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidShortened) || errors.Is(err, domain.ErrNotFound) {
+			return writePage(c, fiber.StatusNotFound, notFoundPage)
+		}
+
+		if errors.Is(err, domain.ErrExpired) {
+			return writePage(c, fiber.StatusGone, gonePage)
+		}
+
+		if errors.Is(err, domain.ErrPasswordRequired) {
+			return writePage(c, fiber.StatusUnauthorized, fmt.Sprintf(passwordPage, ""))
+		}
+
+		if errors.Is(err, domain.ErrInvalidPassword) {
+			return writePage(c, fiber.StatusUnauthorized, fmt.Sprintf(passwordPage, "<p>Wrong password.</p>\n"))
+		}
+
+		if errors.Is(err, domain.ErrTooManyAttempts) {
+			return writePage(c, fiber.StatusTooManyRequests, tooManyAttemptsPage)
+		}
+
+		getLogger(c).Error("redirect failed",
+			logger.Field{Key: "shortened", Value: params.Shortened},
+			logger.Field{Key: "error", Value: err})
+
+		return c.SendStatus(fiber.StatusInternalServerError)
+	}
+
+	return c.Redirect(original, status)
+}
+
+func writePage(c *fiber.Ctx, status int, page string) error {
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+
+	return c.Status(status).SendString(page)
+}
@@ -3,34 +3,63 @@ package httphandlers
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"shortener/internal/domain"
+	"shortener/internal/transfer"
 	"shortener/pkg/logger"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Usecase interface {
-	CreateShortened(ctx context.Context, url string) (string, error)
-	GetOriginalByShortened(ctx context.Context, shortened string) (string, error)
+	CreateShortened(ctx context.Context, params domain.CreateParams) (domain.Link, error)
+	CreateShortenedBatch(ctx context.Context, urls []string) ([]domain.BatchResult, error)
+	GetOriginalByShortened(ctx context.Context, params domain.ResolveParams) (string, error)
+	GetLink(ctx context.Context, shortened string) (domain.Link, error)
+	GetStats(ctx context.Context, shortened string) (domain.ClickStats, error)
+	DeleteShortened(ctx context.Context, shortened string) error
+	UpdateOriginal(ctx context.Context, shortened, url string) (string, error)
+	ListLinks(ctx context.Context, filter domain.ListFilter) (domain.LinkPage, error)
+	ExportLinks(ctx context.Context, w io.Writer, options transfer.ExportOptions) (int, error)
+	ImportLinks(ctx context.Context, r io.Reader, options transfer.ImportOptions) (transfer.ImportResult, error)
 }
 
 type ApiHandlers struct {
-	uc Usecase
+	uc             Usecase
+	redirectStatus int
+	publicURL      string
 }
 
-func NewHandlers(uc Usecase) *ApiHandlers {
-	return &ApiHandlers{
-		uc: uc,
+func NewHandlers(uc Usecase, redirectStatus int, publicURL string) (*ApiHandlers, error) {
+	switch redirectStatus {
+	case fiber.StatusMovedPermanently, fiber.StatusFound,
+		fiber.StatusTemporaryRedirect, fiber.StatusPermanentRedirect:
+	default:
+		return nil, fmt.Errorf("unsupported redirect status %d", redirectStatus)
 	}
+
+	return &ApiHandlers{
+		uc:             uc,
+		redirectStatus: redirectStatus,
+		publicURL:      strings.TrimRight(publicURL, "/"),
+	}, nil
 }
 
 type createShortenerParams struct {
-	URL string `json:"url"`
+	URL        string     `json:"url"`
+	Alias      string     `json:"alias"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	TTLSeconds int64      `json:"ttl_seconds"`
+	Password   string     `json:"password"`
 }
 
 type createShortenerResponse struct {
-	Shortened string `json:"shortened"`
+	Shortened string     `json:"shortened"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 func (h *ApiHandlers) CreateShortened() fiber.Handler {
@@ -40,23 +69,116 @@ func (h *ApiHandlers) CreateShortened() fiber.Handler {
 			return writeError(c, fiber.StatusBadRequest, "invalid json")
 		}
 
-		shortened, err := h.uc.CreateShortened(c.Context(), req.URL)
+		ttl, err := domain.TTLFromSeconds(req.TTLSeconds)
+		if err != nil {
+			return writeError(c, fiber.StatusBadRequest, "invalid expiration")
+		}
+
+		link, err := h.uc.CreateShortened(c.UserContext(), domain.CreateParams{
+			URL:       req.URL,
+			Alias:     req.Alias,
+			ExpiresAt: req.ExpiresAt,
+			TTL:       ttl,
+			Password:  req.Password,
+		})
 		if err != nil {
 			if errors.Is(err, domain.ErrInvalidURL) {
 				return writeError(c, fiber.StatusBadRequest, "invalid url")
 			}
 
+			if errors.Is(err, domain.ErrForbiddenDestination) {
+				return writeError(c, fiber.StatusUnprocessableEntity, "forbidden destination")
+			}
+
+			if errors.Is(err, domain.ErrInvalidAlias) {
+				return writeError(c, fiber.StatusBadRequest, "invalid alias")
+			}
+
+			if errors.Is(err, domain.ErrInvalidExpiration) {
+				return writeError(c, fiber.StatusBadRequest, "invalid expiration")
+			}
+
+			if errors.Is(err, domain.ErrInvalidPassword) {
+				return writeError(c, fiber.StatusBadRequest, "invalid password")
+			}
+
+			if errors.Is(err, domain.ErrAlreadyExist) {
+				return writeError(c, fiber.StatusConflict, "already exists")
+			}
+
 			getLogger(c).Error("create shortened failed",
 				logger.Field{Key: "url", Value: req.URL},
+				logger.Field{Key: "alias", Value: req.Alias},
 				logger.Field{Key: "error", Value: err})
 
 			return writeError(c, fiber.StatusInternalServerError, "internal error")
 		}
 
-		return writeSuccess(c, fiber.StatusOK, createShortenerResponse{Shortened: shortened})
+		return writeSuccess(c, fiber.StatusOK, createShortenerResponse{
+			Shortened: link.Shortened,
+			ExpiresAt: link.ExpiresAt,
+		})
 	}
 }
 
+type createShortenedBatchParams struct {
+	URLs []string `json:"urls"`
+}
+
+type createShortenedBatchItem struct {
+	URL       string `json:"url"`
+	Shortened string `json:"shortened,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (h *ApiHandlers) CreateShortenedBatch() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		req := createShortenedBatchParams{}
+		if err := c.BodyParser(&req); err != nil {
+			return writeError(c, fiber.StatusBadRequest, "invalid json")
+		}
+
+		results, err := h.uc.CreateShortenedBatch(c.UserContext(), req.URLs)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidBatch) {
+				return writeError(c, fiber.StatusBadRequest, "invalid batch")
+			}
+
+			getLogger(c).Error("create shortened batch failed",
+				logger.Field{Key: "size", Value: len(req.URLs)},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		items := make([]createShortenedBatchItem, 0, len(results))
+		for _, res := range results {
+			item := createShortenedBatchItem{URL: res.URL}
+
+			switch {
+			case res.Err == nil:
+				item.Shortened = res.Link.Shortened
+			case errors.Is(res.Err, domain.ErrInvalidURL):
+				item.Error = "invalid url"
+			case errors.Is(res.Err, domain.ErrForbiddenDestination):
+				item.Error = "forbidden destination"
+			default:
+				getLogger(c).Error("create shortened batch item failed",
+					logger.Field{Key: "url", Value: res.URL},
+					logger.Field{Key: "error", Value: res.Err})
+
+				item.Error = "internal error"
+			}
+
+			items = append(items, item)
+		}
+
+		return writeSuccess(c, fiber.StatusOK, items)
+	}
+}
+
+const headerLinkPassword = "X-Link-Password"
+
 type getOriginalResponse struct {
 	Original string `json:"original"`
 }
@@ -65,12 +187,31 @@ func (h *ApiHandlers) GetOriginalal() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		shortened := c.Params("shortened")
 
-		original, err := h.uc.GetOriginalByShortened(c.Context(), shortened)
+		params := resolveParams(c, shortened)
+		params.Password = c.Get(headerLinkPassword)
+
+		original, err := h.uc.GetOriginalByShortened(c.UserContext(), params)
 		if err != nil {
 			if errors.Is(err, domain.ErrInvalidShortened) || errors.Is(err, domain.ErrNotFound) {
 				return writeError(c, fiber.StatusNotFound, "not found")
 			}
 
+			if errors.Is(err, domain.ErrExpired) {
+				return writeError(c, fiber.StatusGone, "expired")
+			}
+
+			if errors.Is(err, domain.ErrPasswordRequired) {
+				return writeError(c, fiber.StatusUnauthorized, "password required")
+			}
+
+			if errors.Is(err, domain.ErrInvalidPassword) {
+				return writeError(c, fiber.StatusUnauthorized, "invalid password")
+			}
+
+			if errors.Is(err, domain.ErrTooManyAttempts) {
+				return writeError(c, fiber.StatusTooManyRequests, "too many attempts")
+			}
+
 			getLogger(c).Error("get original failed",
 				logger.Field{Key: "shortened", Value: shortened},
 				logger.Field{Key: "error", Value: err})
@@ -81,3 +222,12 @@ func (h *ApiHandlers) GetOriginalal() fiber.Handler {
 		return writeSuccess(c, fiber.StatusOK, getOriginalResponse{Original: original})
 	}
 }
+
+func resolveParams(c *fiber.Ctx, shortened string) domain.ResolveParams {
+	return domain.ResolveParams{
+		Shortened: shortened,
+		Referrer:  c.Get(fiber.HeaderReferer),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+		IP:        c.IP(),
+	}
+}
@@ -7,6 +7,22 @@ import (
 func (h *ApiHandlers) MapApiRoutes(router fiber.Router, mw Middleware) {
 	router.Use(mw.SetRequestID())
 
-	router.Post("/create_shortened", h.CreateShortened())
-	router.Get("get_original/:shortened", h.GetOriginalal())
+	// registered before LimitBody so that the import body is streamed
+	router.Post("/admin/import", mw.RequireAdmin(), h.ImportLinks())
+	router.Use(mw.LimitBody())
+
+	router.Post("/create_shortened", mw.LimitCreate(), mw.RequireAPIKey(), h.CreateShortened())
+	router.Post("/create_shortened/batch", mw.LimitCreateBatch(), mw.RequireAPIKey(), h.CreateShortenedBatch())
+	router.Get("get_original/:shortened", mw.LimitResolve(), h.GetOriginalal())
+	router.Get("/links", mw.LimitResolve(), mw.RequireAdmin(), h.ListLinks())
+	router.Get("/links/:shortened/stats", mw.LimitResolve(), h.GetStats())
+	router.Get("/links/:shortened/qr", mw.LimitResolve(), h.GetQR())
+	router.Patch("/links/:shortened", mw.LimitCreate(), mw.RequireAPIKey(), h.UpdateOriginal())
+	router.Delete("/links/:shortened", mw.LimitCreate(), mw.RequireAPIKey(), h.DeleteShortened())
+	router.Get("/admin/export", mw.RequireAdmin(), h.ExportLinks())
+}
+
+func (h *ApiHandlers) MapRedirectRoutes(router fiber.Router, mw Middleware) {
+	router.Get("/:shortened", mw.SetRequestID(), mw.LimitResolve(), h.Redirect())
+	router.Post("/:shortened", mw.SetRequestID(), mw.LimitBody(), mw.LimitResolve(), h.RedirectWithPassword())
 }
@@ -0,0 +1,54 @@
+package httphandlers
+
+import (
+	"errors"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type dailyClicksResponse struct {
+	Date  string `json:"date"`
+	Count int64  `json:"count"`
+}
+
+type getStatsResponse struct {
+	Shortened string                `json:"shortened"`
+	Total     int64                 `json:"total"`
+	Daily     []dailyClicksResponse `json:"daily"`
+}
+
+func (h *ApiHandlers) GetStats() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		shortened := c.Params("shortened")
+
+		stats, err := h.uc.GetStats(c.UserContext(), shortened)
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidShortened) || errors.Is(err, domain.ErrNotFound) {
+				return writeError(c, fiber.StatusNotFound, "not found")
+			}
+
+			getLogger(c).Error("get stats failed",
+				logger.Field{Key: "shortened", Value: shortened},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		daily := make([]dailyClicksResponse, 0, len(stats.Daily))
+		for _, d := range stats.Daily {
+			daily = append(daily, dailyClicksResponse{
+				Date:  d.Day.Format("2006-01-02"),
+				Count: d.Count,
+			})
+		}
+
+		return writeSuccess(c, fiber.StatusOK, getStatsResponse{
+			Shortened: shortened,
+			Total:     stats.Total,
+			Daily:     daily,
+		})
+	}
+}
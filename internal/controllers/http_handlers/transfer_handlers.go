@@ -0,0 +1,96 @@
+package httphandlers
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+
+	"shortener/internal/domain"
+	"shortener/internal/transfer"
+	"shortener/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var transferContentTypes = map[string]string{
+	transfer.FormatJSONL: "application/x-ndjson",
+	transfer.FormatCSV:   "text/csv; charset=utf-8",
+}
+
+func (h *ApiHandlers) ExportLinks() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		format := c.Query("format", transfer.FormatJSONL)
+
+		contentType, ok := transferContentTypes[format]
+		if !ok {
+			return writeError(c, fiber.StatusBadRequest, "format must be jsonl or csv")
+		}
+
+		// password hashes leave the server only when asked for explicitly
+		options := transfer.ExportOptions{
+			Format:         format,
+			PasswordHashes: c.QueryBool("password_hashes"),
+		}
+
+		// the body is written after the handler returns, so nothing from c
+		// may be used inside the stream writer
+		ctx := c.UserContext()
+		log := getLogger(c)
+
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="links.`+format+`"`)
+		c.Status(fiber.StatusOK).Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			count, err := h.uc.ExportLinks(ctx, w, options)
+			if err == nil {
+				err = w.Flush()
+			}
+
+			if err != nil {
+				log.Error("export links failed",
+					logger.Field{Key: "exported", Value: count},
+					logger.Field{Key: "error", Value: err})
+			}
+		})
+
+		return nil
+	}
+}
+
+func (h *ApiHandlers) ImportLinks() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		options := transfer.ImportOptions{
+			Format:   c.Query("format", transfer.FormatJSONL),
+			Conflict: c.Query("conflict", transfer.ConflictSkip),
+		}
+
+		var body io.Reader = c.Context().RequestBodyStream()
+		if body == nil {
+			body = bytes.NewReader(c.Body())
+		}
+
+		result, err := h.uc.ImportLinks(c.UserContext(), body, options)
+		if err != nil {
+			if errors.Is(err, domain.ErrForbiddenDestination) {
+				return writeError(c, fiber.StatusUnprocessableEntity, err.Error())
+			}
+
+			if errors.Is(err, transfer.ErrInvalidOptions) || errors.Is(err, transfer.ErrInvalidRecord) {
+				return writeError(c, fiber.StatusBadRequest, err.Error())
+			}
+
+			if errors.Is(err, domain.ErrAlreadyExist) {
+				return writeError(c, fiber.StatusConflict, err.Error())
+			}
+
+			getLogger(c).Error("import links failed",
+				logger.Field{Key: "created", Value: result.Created},
+				logger.Field{Key: "replaced", Value: result.Replaced},
+				logger.Field{Key: "error", Value: err})
+
+			return writeError(c, fiber.StatusInternalServerError, "internal error")
+		}
+
+		return writeSuccess(c, fiber.StatusOK, result)
+	}
+}
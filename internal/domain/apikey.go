@@ -0,0 +1,16 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+type APIKey struct {
+	Name string
+	Hash string
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+type Click struct {
+	Shortened string
+	At        time.Time
+	Referrer  string
+	UserAgent string
+	IP        string
+}
+
+type DailyClicks struct {
+	Day   time.Time
+	Count int64
+}
+
+type ClickStats struct {
+	Total int64
+	Daily []DailyClicks
+}
@@ -3,8 +3,20 @@ package domain
 import "errors"
 
 var (
-	ErrNotFound         = errors.New("not found")
-	ErrAlreadyExist     = errors.New("already exists")
-	ErrInvalidURL       = errors.New("invalid url")
-	ErrInvalidShortened = errors.New("invalid shortened")
+	ErrNotFound          = errors.New("not found")
+	ErrAlreadyExist      = errors.New("already exists")
+	ErrInvalidURL        = errors.New("invalid url")
+	ErrInvalidShortened  = errors.New("invalid shortened")
+	ErrInvalidAlias      = errors.New("invalid alias")
+	ErrInvalidExpiration = errors.New("invalid expiration")
+	ErrExpired           = errors.New("expired")
+	ErrInvalidBatch      = errors.New("invalid batch")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrInvalidFilter     = errors.New("invalid filter")
+
+	ErrForbiddenDestination = errors.New("forbidden destination")
+
+	ErrPasswordRequired = errors.New("password required")
+	ErrInvalidPassword  = errors.New("invalid password")
+	ErrTooManyAttempts  = errors.New("too many attempts")
 )
@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+type Link struct {
+	Original  string
+	Shortened string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+
+	PasswordHash string
+}
+
+func (l Link) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+func (l Link) Protected() bool {
+	return l.PasswordHash != ""
+}
+
+// Reusable reports whether the link may be handed out again to anyone who
+// shortens the same URL. Protected and expiring links are always private.
+func (l Link) Reusable() bool {
+	return !l.Protected() && l.ExpiresAt == nil
+}
+
+// MaxTTL bounds a client supplied lifetime well below time.Duration overflow.
+const MaxTTL = 100 * 365 * 24 * time.Hour
+
+// TTLFromSeconds converts ttl_seconds from a request, rejecting values that
+// are negative or longer than MaxTTL with ErrInvalidExpiration.
+func TTLFromSeconds(seconds int64) (time.Duration, error) {
+	if seconds < 0 || seconds > int64(MaxTTL/time.Second) {
+		return 0, ErrInvalidExpiration
+	}
+
+	return time.Duration(seconds) * time.Second, nil
+}
+
+type CreateParams struct {
+	URL       string
+	Alias     string
+	ExpiresAt *time.Time
+	TTL       time.Duration
+	Password  string
+}
+
+type ResolveParams struct {
+	Shortened string
+	Referrer  string
+	UserAgent string
+	IP        string
+	Password  string
+}
+
+type BatchResult struct {
+	URL  string
+	Link Link
+	Err  error
+}
+
+// CanonicalizeResult counts the outcome of rewriting stored URLs to their
+// canonical form. Conflicts holds codes whose canonical URL already belongs to
+// another link, Invalid codes whose URL no longer passes validation.
+type CanonicalizeResult struct {
+	Checked   int      `json:"checked"`
+	Updated   int      `json:"updated"`
+	Conflicts []string `json:"conflicts,omitempty"`
+	Invalid   []string `json:"invalid,omitempty"`
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+type ListFilter struct {
+	// Domain matches the destination host and its subdomains.
+	Domain string
+	// Query is a case-insensitive substring of the original or the code.
+	Query         string
+	CreatedFrom   *time.Time
+	CreatedBefore *time.Time
+	Desc          bool
+	Limit         int
+	Cursor        string
+}
+
+type LinkPage struct {
+	Links      []Link
+	NextCursor string
+}
+
+func (f ListFilter) Match(link Link) bool {
+	if f.CreatedFrom != nil && link.CreatedAt.Before(*f.CreatedFrom) {
+		return false
+	}
+
+	if f.CreatedBefore != nil && !link.CreatedAt.Before(*f.CreatedBefore) {
+		return false
+	}
+
+	if f.Query != "" {
+		query := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(link.Original), query) &&
+			!strings.Contains(strings.ToLower(link.Shortened), query) {
+			return false
+		}
+	}
+
+	if f.Domain != "" {
+		parsed, err := url.Parse(link.Original)
+		if err != nil {
+			return false
+		}
+
+		host := strings.ToLower(parsed.Hostname())
+		domain := strings.ToLower(f.Domain)
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+
+	return true
+}
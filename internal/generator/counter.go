@@ -0,0 +1,117 @@
+package generator
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/bits"
+	"sync"
+)
+
+var ErrKeyspaceExhausted = errors.New("generator keyspace exhausted")
+
+type IDSource interface {
+	NextBlock(ctx context.Context) (start, size uint64, err error)
+}
+
+type CounterGenerator struct {
+	mu       sync.Mutex
+	source   IDSource
+	next     uint64
+	end      uint64
+	alphabet string
+	len      int
+	keyspace uint64
+	scramble *feistel
+}
+
+func NewCounterGenerator(source IDSource, alphabet string, length int, scrambleKey string) (*CounterGenerator, error) {
+	if len(alphabet) < 2 {
+		return nil, errors.New("alphabet must contain at least two characters")
+	}
+
+	if length <= 0 {
+		return nil, errors.New("length must be positive")
+	}
+
+	keyspace := keyspaceSize(uint64(len(alphabet)), length)
+
+	g := &CounterGenerator{
+		source:   source,
+		alphabet: alphabet,
+		len:      length,
+		keyspace: keyspace,
+	}
+
+	if scrambleKey != "" {
+		g.scramble = newFeistel([]byte(scrambleKey), keyspace)
+	}
+
+	return g, nil
+}
+
+func (g *CounterGenerator) Generate(ctx context.Context, _ string, _ int) (string, error) {
+	id, err := g.nextID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if g.keyspace != math.MaxUint64 && id >= g.keyspace {
+		return "", ErrKeyspaceExhausted
+	}
+
+	if g.scramble != nil {
+		id = g.scramble.permute(id)
+	}
+
+	return g.encode(id), nil
+}
+
+func (g *CounterGenerator) nextID(ctx context.Context) (uint64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.next >= g.end {
+		start, size, err := g.source.NextBlock(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		if size == 0 {
+			return 0, errors.New("id source returned an empty block")
+		}
+
+		g.next, g.end = start, start+size
+	}
+
+	id := g.next
+	g.next++
+
+	return id, nil
+}
+
+func (g *CounterGenerator) encode(id uint64) string {
+	base := uint64(len(g.alphabet))
+
+	b := make([]byte, g.len)
+	for i := g.len - 1; i >= 0; i-- {
+		b[i] = g.alphabet[id%base]
+		id /= base
+	}
+
+	return string(b)
+}
+
+func keyspaceSize(base uint64, length int) uint64 {
+	size := uint64(1)
+	for range length {
+		hi, lo := bits.Mul64(size, base)
+		if hi != 0 {
+			return math.MaxUint64
+		}
+
+		size = lo
+	}
+
+	return size
+}
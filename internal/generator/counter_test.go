@@ -0,0 +1,111 @@
+package generator_test
+
+import (
+	"context"
+	"errors"
+	"shortener/internal/generator"
+	"shortener/internal/validator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type blockSource struct {
+	next  uint64
+	size  uint64
+	calls int
+	err   error
+}
+
+func (s *blockSource) NextBlock(_ context.Context) (uint64, uint64, error) {
+	if s.err != nil {
+		return 0, 0, s.err
+	}
+
+	s.calls++
+	start := s.next
+	s.next += s.size
+
+	return start, s.size, nil
+}
+
+func TestCounterGeneratorGenerate(t *testing.T) {
+	alphabet := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+	size := 10
+
+	validator, _ := validator.NewValidator(alphabet, size)
+
+	tests := []struct {
+		name        string
+		scrambleKey string
+	}{
+		{
+			name: "plain",
+		},
+		{
+			name:        "scrambled",
+			scrambleKey: "secret",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &blockSource{size: 100}
+			gen, err := generator.NewCounterGenerator(source, alphabet, size, tt.scrambleKey)
+			require.NoError(t, err)
+
+			seen := make(map[string]struct{})
+			for range 1000 {
+				s, err := gen.Generate(context.Background(), "", 0)
+				require.NoError(t, err)
+				assert.Len(t, s, size)
+				assert.True(t, validator.ValidateShortened(s))
+
+				seen[s] = struct{}{}
+			}
+
+			assert.Len(t, seen, 1000)
+			assert.Equal(t, 10, source.calls)
+		})
+	}
+}
+
+func TestCounterGeneratorSequence(t *testing.T) {
+	gen, err := generator.NewCounterGenerator(&blockSource{size: 1}, "ab", 3, "")
+	require.NoError(t, err)
+
+	want := []string{"aaa", "aab", "aba", "abb"}
+	for _, w := range want {
+		s, err := gen.Generate(context.Background(), "", 0)
+		require.NoError(t, err)
+		assert.Equal(t, w, s)
+	}
+}
+
+func TestCounterGeneratorScrambleIsBijective(t *testing.T) {
+	gen, err := generator.NewCounterGenerator(&blockSource{size: 7}, "abc", 4, "secret")
+	require.NoError(t, err)
+
+	seen := make(map[string]struct{})
+	for range 81 {
+		s, err := gen.Generate(context.Background(), "", 0)
+		require.NoError(t, err)
+
+		seen[s] = struct{}{}
+	}
+
+	assert.Len(t, seen, 81)
+
+	_, err = gen.Generate(context.Background(), "", 0)
+	assert.ErrorIs(t, err, generator.ErrKeyspaceExhausted)
+}
+
+func TestCounterGeneratorSourceError(t *testing.T) {
+	gen, err := generator.NewCounterGenerator(&blockSource{err: errors.New("db error")}, "abc", 4, "")
+	require.NoError(t, err)
+
+	s, err := gen.Generate(context.Background(), "", 0)
+	assert.EqualError(t, err, "db error")
+	assert.Empty(t, s)
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+const feistelRounds = 4
+
+type feistel struct {
+	key      []byte
+	half     uint
+	mask     uint64
+	keyspace uint64
+}
+
+func newFeistel(key []byte, keyspace uint64) *feistel {
+	width := uint(64)
+	if keyspace != math.MaxUint64 {
+		width = uint(bits.Len64(keyspace - 1))
+	}
+
+	width += width % 2
+	if width < 2 {
+		width = 2
+	}
+
+	half := width / 2
+
+	return &feistel{
+		key:      key,
+		half:     half,
+		mask:     uint64(1)<<half - 1,
+		keyspace: keyspace,
+	}
+}
+
+// permute walks the cycle until the value falls back inside the keyspace,
+// which keeps the permutation bijective on [0, keyspace).
+func (f *feistel) permute(id uint64) uint64 {
+	for {
+		id = f.encrypt(id)
+		if f.keyspace == math.MaxUint64 || id < f.keyspace {
+			return id
+		}
+	}
+}
+
+func (f *feistel) encrypt(v uint64) uint64 {
+	left, right := v>>f.half&f.mask, v&f.mask
+
+	for round := range feistelRounds {
+		left, right = right, left^f.round(round, right)
+	}
+
+	return left<<f.half | right
+}
+
+func (f *feistel) round(round int, v uint64) uint64 {
+	var buf [9]byte
+	buf[0] = byte(round)
+	binary.BigEndian.PutUint64(buf[1:], v)
+
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write(buf[:])
+
+	return binary.BigEndian.Uint64(mac.Sum(nil)) & f.mask
+}
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"context"
 	"crypto/rand"
 	"math/big"
 )
@@ -17,7 +18,7 @@ func NewGenerator(alphabet string, len int) *Generator {
 	}
 }
 
-func (g *Generator) Generate() (string, error) {
+func (g *Generator) Generate(_ context.Context, _ string, _ int) (string, error) {
 	b := make([]byte, g.len)
 	for i := range b {
 		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(g.alphabet))))
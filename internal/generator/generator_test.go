@@ -1,6 +1,7 @@
 package generator_test
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"shortener/internal/generator"
@@ -19,7 +20,7 @@ func TestGeneratorGenerate(t *testing.T) {
 
 	t.Run("classic", func(t *testing.T) {
 		for i := 0; i < 100; i++ {
-			s, err := gen.Generate()
+			s, err := gen.Generate(context.Background(), "", 0)
 			assert.NoError(t, err)
 			assert.Len(t, s, size)
 			assert.True(t, validator.ValidateShortened(s))
@@ -41,7 +42,7 @@ func TestGeneratorRandError(t *testing.T) {
 
 	rand.Reader = &failReader{}
 
-	s, err := gen.Generate()
+	s, err := gen.Generate(context.Background(), "", 0)
 	assert.Error(t, err)
 	assert.EqualError(t, err, "read error")
 	assert.Empty(t, s)
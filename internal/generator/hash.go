@@ -0,0 +1,60 @@
+package generator
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+)
+
+type HashGenerator struct {
+	secret   []byte
+	alphabet string
+	len      int
+}
+
+func NewHashGenerator(secret, alphabet string, length int) (*HashGenerator, error) {
+	if secret == "" {
+		return nil, errors.New("secret must not be empty")
+	}
+
+	if len(alphabet) < 2 {
+		return nil, errors.New("alphabet must contain at least two characters")
+	}
+
+	if length <= 0 {
+		return nil, errors.New("length must be positive")
+	}
+
+	return &HashGenerator{
+		secret:   []byte(secret),
+		alphabet: alphabet,
+		len:      length,
+	}, nil
+}
+
+func (g *HashGenerator) Generate(_ context.Context, url string, attempt int) (string, error) {
+	mac := hmac.New(sha256.New, g.secret)
+
+	if attempt > 0 {
+		var salt [8]byte
+		binary.BigEndian.PutUint64(salt[:], uint64(attempt))
+		mac.Write(salt[:])
+	}
+
+	mac.Write([]byte(url))
+
+	n := new(big.Int).SetBytes(mac.Sum(nil))
+	base := big.NewInt(int64(len(g.alphabet)))
+	digit := new(big.Int)
+
+	b := make([]byte, g.len)
+	for i := g.len - 1; i >= 0; i-- {
+		n.QuoRem(n, base, digit)
+		b[i] = g.alphabet[digit.Int64()]
+	}
+
+	return string(b), nil
+}
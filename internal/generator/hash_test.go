@@ -0,0 +1,80 @@
+package generator_test
+
+import (
+	"context"
+	"shortener/internal/generator"
+	"shortener/internal/validator"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashGeneratorGenerate(t *testing.T) {
+	ctx := context.Background()
+	alphabet := "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+	size := 10
+
+	validator, _ := validator.NewValidator(alphabet, size)
+
+	gen, err := generator.NewHashGenerator("secret", alphabet, size)
+	require.NoError(t, err)
+
+	other, err := generator.NewHashGenerator("other", alphabet, size)
+	require.NoError(t, err)
+
+	first, err := gen.Generate(ctx, "https://example.com", 0)
+	require.NoError(t, err)
+	assert.Len(t, first, size)
+	assert.True(t, validator.ValidateShortened(first))
+
+	tests := []struct {
+		name    string
+		gen     *generator.HashGenerator
+		url     string
+		attempt int
+		same    bool
+	}{
+		{
+			name: "same url",
+			gen:  gen,
+			url:  "https://example.com",
+			same: true,
+		},
+		{
+			name: "other url",
+			gen:  gen,
+			url:  "https://example.org",
+		},
+		{
+			name:    "salted attempt",
+			gen:     gen,
+			url:     "https://example.com",
+			attempt: 1,
+		},
+		{
+			name: "other secret",
+			gen:  other,
+			url:  "https://example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := tt.gen.Generate(ctx, tt.url, tt.attempt)
+			require.NoError(t, err)
+			assert.True(t, validator.ValidateShortened(s))
+
+			if tt.same {
+				assert.Equal(t, first, s)
+			} else {
+				assert.NotEqual(t, first, s)
+			}
+		})
+	}
+}
+
+func TestNewHashGeneratorEmptySecret(t *testing.T) {
+	_, err := generator.NewHashGenerator("", "abc", 5)
+	assert.Error(t, err)
+}
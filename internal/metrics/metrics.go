@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "shortener"
+
+type Metrics struct {
+	registry            *prometheus.Registry
+	requests            *prometheus.CounterVec
+	latency             *prometheus.HistogramVec
+	linksCreated        prometheus.Counter
+	dedupHits           prometheus.Counter
+	collisionRetries    prometheus.Counter
+	maxAttemptsExceeded prometheus.Counter
+	cacheHits           prometheus.Counter
+	cacheMisses         prometheus.Counter
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route, method and status.",
+		}, []string{"route", "method", "status"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route, method and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		linksCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "links_created_total",
+			Help:      "Short links created.",
+		}),
+		dedupHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dedup_hits_total",
+			Help:      "Create requests answered with an existing link for the same original.",
+		}),
+		collisionRetries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generator_collision_retries_total",
+			Help:      "Generated codes rejected because they already exist.",
+		}),
+		maxAttemptsExceeded: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "generator_max_attempts_exceeded_total",
+			Help:      "Create requests that failed after exhausting generation attempts.",
+		}),
+		cacheHits: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Lookups by short code answered from the cache, including cached misses.",
+		}),
+		cacheMisses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Lookups by short code that went to the repository.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.latency,
+		m.linksCreated,
+		m.dedupHits,
+		m.collisionRetries,
+		m.maxAttemptsExceeded,
+		m.cacheHits,
+		m.cacheMisses,
+	)
+
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) Register(c prometheus.Collector) error {
+	return m.registry.Register(c)
+}
+
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+
+	m.requests.WithLabelValues(route, method, code).Inc()
+	m.latency.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+func (m *Metrics) LinkCreated() {
+	m.linksCreated.Inc()
+}
+
+func (m *Metrics) DedupHit() {
+	m.dedupHits.Inc()
+}
+
+func (m *Metrics) CollisionRetry() {
+	m.collisionRetries.Inc()
+}
+
+func (m *Metrics) MaxAttemptsExceeded() {
+	m.maxAttemptsExceeded.Inc()
+}
+
+func (m *Metrics) CacheHit() {
+	m.cacheHits.Inc()
+}
+
+func (m *Metrics) CacheMiss() {
+	m.cacheMisses.Inc()
+}
@@ -0,0 +1,356 @@
+package policy
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	netURL "net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/pkg/logger"
+
+	"golang.org/x/net/idna"
+)
+
+type ruleKind int
+
+const (
+	kindExact ruleKind = iota
+	kindSuffix
+	kindRegex
+)
+
+type rule struct {
+	kind  ruleKind
+	value string
+	re    *regexp.Regexp
+}
+
+func (r rule) match(host string) bool {
+	switch r.kind {
+	case kindExact:
+		return host == r.value
+	case kindSuffix:
+		return host == r.value || strings.HasSuffix(host, "."+r.value)
+	default:
+		return r.re.MatchString(host)
+	}
+}
+
+type rules struct {
+	allow []rule
+	deny  []rule
+}
+
+type Options struct {
+	File           string
+	ReloadInterval time.Duration
+	SelfHosts      []string
+}
+
+type Policy struct {
+	rules     atomic.Pointer[rules]
+	selfHosts map[string]struct{}
+	file      string
+	interval  time.Duration
+	modTime   time.Time
+	log       logger.Logger
+}
+
+func NewPolicy(options Options, log logger.Logger) (*Policy, error) {
+	p := &Policy{
+		selfHosts: make(map[string]struct{}, len(options.SelfHosts)),
+		file:      options.File,
+		interval:  options.ReloadInterval,
+		log:       log,
+	}
+
+	for _, host := range options.SelfHosts {
+		ascii, err := asciiHost(host)
+		if err != nil {
+			return nil, fmt.Errorf("self host %q: %w", host, err)
+		}
+		p.selfHosts[ascii] = struct{}{}
+	}
+
+	p.rules.Store(&rules{})
+
+	if p.file != "" {
+		if _, err := p.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Check returns domain.ErrForbiddenDestination when links to url must not be created.
+func (p *Policy) Check(url string) error {
+	parsed, err := netURL.Parse(url)
+	if err != nil {
+		return nil
+	}
+
+	host := normalizeHost(parsed.Hostname())
+	if host == "" {
+		return nil
+	}
+
+	if _, ok := p.selfHosts[host]; ok {
+		return fmt.Errorf("%w: %s is the shortener itself", domain.ErrForbiddenDestination, host)
+	}
+
+	if isInternal(host) {
+		return fmt.Errorf("%w: %s is an internal address", domain.ErrForbiddenDestination, host)
+	}
+
+	current := p.rules.Load()
+
+	for _, r := range current.deny {
+		if r.match(host) {
+			return fmt.Errorf("%w: %s is denied", domain.ErrForbiddenDestination, host)
+		}
+	}
+
+	if len(current.allow) == 0 {
+		return nil
+	}
+
+	for _, r := range current.allow {
+		if r.match(host) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s is not allowed", domain.ErrForbiddenDestination, host)
+}
+
+func (p *Policy) Run(ctx context.Context) {
+	if p.file == "" || p.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := p.reload()
+			if err != nil {
+				p.log.Error("policy reload failed, keeping previous rules",
+					logger.Field{Key: "file", Value: p.file},
+					logger.Field{Key: "error", Value: err})
+
+				continue
+			}
+
+			if reloaded {
+				p.log.Info("policy reloaded",
+					logger.Field{Key: "file", Value: p.file})
+			}
+		}
+	}
+}
+
+func (p *Policy) reload() (bool, error) {
+	info, err := os.Stat(p.file)
+	if err != nil {
+		return false, err
+	}
+
+	if info.ModTime().Equal(p.modTime) {
+		return false, nil
+	}
+
+	f, err := os.Open(p.file)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	parsed, err := parseRules(f)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", p.file, err)
+	}
+
+	p.rules.Store(parsed)
+	p.modTime = info.ModTime()
+
+	return true, nil
+}
+
+// parseRules reads lines of the form "<allow|deny> <exact|suffix|regex> <value>".
+// Empty lines and lines starting with # are ignored.
+func parseRules(src io.Reader) (*rules, error) {
+	parsed := &rules{}
+
+	scanner := bufio.NewScanner(src)
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected <allow|deny> <exact|suffix|regex> <value>", line)
+		}
+
+		r := rule{}
+		switch fields[1] {
+		case "exact", "suffix":
+			host, err := asciiHost(strings.TrimPrefix(fields[2], "."))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: host %q: %w", line, fields[2], err)
+			}
+
+			r.kind, r.value = kindExact, host
+			if fields[1] == "suffix" {
+				r.kind = kindSuffix
+			}
+		case "regex":
+			re, err := regexp.Compile(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			r.kind, r.re = kindRegex, re
+		default:
+			return nil, fmt.Errorf("line %d: unknown match type %q", line, fields[1])
+		}
+
+		switch fields[0] {
+		case "allow":
+			parsed.allow = append(parsed.allow, r)
+		case "deny":
+			parsed.deny = append(parsed.deny, r)
+		default:
+			return nil, fmt.Errorf("line %d: unknown action %q", line, fields[0])
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// asciiHost brings a configured host to the punycode form that canonical URLs
+// use, so that "пример.рф" in a rule matches "xn--e1afmkfd.xn--p1ai".
+func asciiHost(host string) (string, error) {
+	host = normalizeHost(host)
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+
+	return idna.Lookup.ToASCII(host)
+}
+
+// reservedNets are special-purpose IPv4 ranges the net.IP predicates miss:
+// "this network" and the carrier-grade NAT shared space.
+var reservedNets = []*net.IPNet{
+	mustCIDR("0.0.0.0/8"),
+	mustCIDR("100.64.0.0/10"),
+}
+
+func mustCIDR(cidr string) *net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return n
+}
+
+func isInternal(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		ip = parseInetAton(host)
+	}
+
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range reservedNets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsInterfaceLocalMulticast()
+}
+
+// parseInetAton reads the IPv4 shorthands accepted by inet_aton and by
+// browsers: one to four parts in decimal, octal (leading 0) or hex (0x), the
+// last part filling the remaining bytes, e.g. 127.1, 2130706433 or 0x7f000001.
+func parseInetAton(host string) net.IP {
+	parts := strings.Split(host, ".")
+	if len(parts) > 4 {
+		return nil
+	}
+
+	values := make([]uint64, len(parts))
+	for i, part := range parts {
+		base := 10
+		switch {
+		case len(part) > 2 && (part[:2] == "0x" || part[:2] == "0X"):
+			part, base = part[2:], 16
+		case len(part) > 1 && part[0] == '0':
+			part, base = part[1:], 8
+		}
+
+		// ParseUint alone would also take signs and underscores
+		if part == "" || strings.ContainsAny(part, "+-_") {
+			return nil
+		}
+
+		v, err := strconv.ParseUint(part, base, 32)
+		if err != nil {
+			return nil
+		}
+
+		values[i] = v
+	}
+
+	var addr uint64
+	for i, v := range values[:len(values)-1] {
+		if v > 0xff {
+			return nil
+		}
+
+		addr |= v << (8 * (3 - i))
+	}
+
+	last := values[len(values)-1]
+	if last >= 1<<(8*(5-len(values))) {
+		return nil
+	}
+
+	addr |= last
+
+	return net.IPv4(byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr))
+}
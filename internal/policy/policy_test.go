@@ -0,0 +1,140 @@
+package policy_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/internal/policy"
+	"shortener/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRules(t *testing.T, path, rules string, modTime time.Time) {
+	t.Helper()
+
+	tmp := path + ".tmp"
+	require.NoError(t, os.WriteFile(tmp, []byte(rules), 0o644))
+	require.NoError(t, os.Chtimes(tmp, modTime, modTime))
+	require.NoError(t, os.Rename(tmp, path))
+}
+
+func TestCheck(t *testing.T) {
+	rules := `
+# phishing
+deny exact evil.com
+deny suffix .phish.example
+deny regex ^login-[a-z]+\.example\.org$
+`
+
+	tests := []struct {
+		name      string
+		rules     string
+		url       string
+		forbidden bool
+	}{
+		{name: "public host", rules: rules, url: "https://example.com/a"},
+		{name: "exact deny", rules: rules, url: "https://EVIL.com/", forbidden: true},
+		{name: "exact does not cover subdomains", rules: rules, url: "https://www.evil.com/"},
+		{name: "suffix deny", rules: rules, url: "https://a.b.phish.example/", forbidden: true},
+		{name: "suffix deny apex", rules: rules, url: "https://phish.example/", forbidden: true},
+		{name: "suffix is label aligned", rules: rules, url: "https://notphish.example/"},
+		{name: "regex deny", rules: rules, url: "https://login-bank.example.org/", forbidden: true},
+		{name: "loopback ip", url: "http://127.0.0.1:8080/", forbidden: true},
+		{name: "loopback ipv6", url: "http://[::1]/", forbidden: true},
+		{name: "private ip", url: "http://10.1.2.3/", forbidden: true},
+		{name: "link local ip", url: "http://169.254.169.254/latest", forbidden: true},
+		{name: "localhost", url: "http://localhost/", forbidden: true},
+		{name: "public ip", url: "http://8.8.8.8/"},
+		{name: "short loopback", url: "http://127.1/", forbidden: true},
+		{name: "decimal loopback", url: "http://2130706433/", forbidden: true},
+		{name: "hex loopback", url: "http://0x7f000001/", forbidden: true},
+		{name: "octal loopback", url: "http://0177.0.0.01/", forbidden: true},
+		{name: "short private", url: "http://10.1/", forbidden: true},
+		{name: "decimal public ip", url: "http://134744072/"},
+		{name: "numeric labels over range", url: "http://256.1.1.1/"},
+		{name: "carrier grade nat", url: "http://100.64.0.1/", forbidden: true},
+		{name: "this network", url: "http://0.1.2.3/", forbidden: true},
+		{name: "mapped loopback", url: "http://[::ffff:127.0.0.1]/", forbidden: true},
+		{name: "self host", url: "https://sho.rt/abc", forbidden: true},
+		{name: "allowlist match", rules: "allow suffix example.com", url: "https://docs.example.com/"},
+		{name: "allowlist miss", rules: "allow suffix example.com", url: "https://example.net/", forbidden: true},
+		{name: "unicode exact rule", rules: "deny exact пример.рф", url: "https://xn--e1afmkfd.xn--p1ai/", forbidden: true},
+		{name: "unicode suffix rule", rules: "deny suffix .ПРИМЕР.рф", url: "https://www.xn--e1afmkfd.xn--p1ai/", forbidden: true},
+		{name: "unicode self host", url: "https://xn--h1ahn.xn--p1ai/abc", forbidden: true},
+		{name: "deny wins over allow", rules: "allow suffix example.com\ndeny exact bad.example.com", url: "https://bad.example.com/", forbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := policy.Options{SelfHosts: []string{"Sho.rt", "мир.рф"}}
+			if tt.rules != "" {
+				options.File = filepath.Join(t.TempDir(), "policy.txt")
+				writeRules(t, options.File, tt.rules, time.Now())
+			}
+
+			p, err := policy.NewPolicy(options, logger.NewNop())
+			require.NoError(t, err)
+
+			err = p.Check(tt.url)
+			if tt.forbidden {
+				assert.ErrorIs(t, err, domain.ErrForbiddenDestination)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewPolicyInvalidRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules string
+	}{
+		{name: "unknown action", rules: "block exact evil.com"},
+		{name: "unknown match", rules: "deny glob *.evil.com"},
+		{name: "missing value", rules: "deny exact"},
+		{name: "bad regex", rules: "deny regex ("},
+		{name: "invalid host", rules: "deny exact xn--zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "policy.txt")
+			writeRules(t, file, tt.rules, time.Now())
+
+			_, err := policy.NewPolicy(policy.Options{File: file}, logger.NewNop())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.txt")
+	start := time.Now().Add(-time.Hour)
+	writeRules(t, file, "deny exact evil.com", start)
+
+	p, err := policy.NewPolicy(policy.Options{File: file, ReloadInterval: 10 * time.Millisecond}, logger.NewNop())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go p.Run(ctx)
+
+	writeRules(t, file, "deny regex (", start.Add(time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	assert.ErrorIs(t, p.Check("https://evil.com/"), domain.ErrForbiddenDestination)
+
+	writeRules(t, file, "deny exact other.com", start.Add(2*time.Minute))
+	assert.Eventually(t, func() bool {
+		return p.Check("https://evil.com/") == nil
+	}, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, p.Check("https://other.com/"), domain.ErrForbiddenDestination)
+}
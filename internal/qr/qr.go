@@ -0,0 +1,187 @@
+package qr
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	FormatPNG = "png"
+	FormatSVG = "svg"
+
+	MinSize   = 64
+	MaxSize   = 2048
+	MaxMargin = 16
+)
+
+var ErrInvalidOptions = errors.New("invalid qr options")
+
+var levels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+type Options struct {
+	Format     string
+	Size       int
+	Margin     int
+	Level      string
+	Foreground color.RGBA
+	Background color.RGBA
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Format:     FormatPNG,
+		Size:       256,
+		Margin:     4,
+		Level:      "M",
+		Foreground: color.RGBA{A: 0xff},
+		Background: color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+}
+
+func (o Options) Validate() error {
+	if o.Format != FormatPNG && o.Format != FormatSVG {
+		return fmt.Errorf("%w: format must be png or svg", ErrInvalidOptions)
+	}
+
+	if o.Size < MinSize || o.Size > MaxSize {
+		return fmt.Errorf("%w: size must be between %d and %d", ErrInvalidOptions, MinSize, MaxSize)
+	}
+
+	if o.Margin < 0 || o.Margin > MaxMargin {
+		return fmt.Errorf("%w: margin must be between 0 and %d", ErrInvalidOptions, MaxMargin)
+	}
+
+	if _, ok := levels[o.Level]; !ok {
+		return fmt.Errorf("%w: level must be one of L, M, Q, H", ErrInvalidOptions)
+	}
+
+	return nil
+}
+
+func (o Options) ContentType() string {
+	if o.Format == FormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+// ParseColor accepts RGB or RRGGBB hex colours with an optional leading #.
+func ParseColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("%w: colour must be hex RGB", ErrInvalidOptions)
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("%w: colour must be hex RGB", ErrInvalidOptions)
+	}
+
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+func Write(w io.Writer, content string, options Options) error {
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	code, err := qrcode.New(content, levels[options.Level])
+	if err != nil {
+		return err
+	}
+	code.DisableBorder = true
+
+	modules := code.Bitmap()
+
+	// every module must be drawn with the same whole number of pixels,
+	// otherwise scanners misread the uneven grid
+	total := len(modules) + 2*options.Margin
+	if options.Size/total < 1 {
+		return fmt.Errorf("%w: size must be at least %d for this content and margin", ErrInvalidOptions, total)
+	}
+
+	if options.Format == FormatSVG {
+		return writeSVG(w, modules, options)
+	}
+
+	return png.Encode(w, render(modules, options))
+}
+
+// render draws each module as a scale x scale square and centres the code,
+// the pixels left over by the integer scale are filled with the background.
+func render(modules [][]bool, options Options) image.Image {
+	total := len(modules) + 2*options.Margin
+	scale := options.Size / total
+	offset := (options.Size-scale*total)/2 + scale*options.Margin
+
+	img := image.NewPaletted(image.Rect(0, 0, options.Size, options.Size),
+		color.Palette{options.Background, options.Foreground})
+
+	for y := range options.Size {
+		if y < offset {
+			continue
+		}
+
+		my := (y - offset) / scale
+		if my >= len(modules) {
+			break
+		}
+
+		for x := offset; x < offset+scale*len(modules); x++ {
+			if modules[my][(x-offset)/scale] {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	return img
+}
+
+func writeSVG(w io.Writer, modules [][]bool, options Options) error {
+	total := len(modules) + 2*options.Margin
+
+	var path strings.Builder
+	for y, row := range modules {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+
+			fmt.Fprintf(&path, "M%d %dh%dv1h-%dz", start+options.Margin, y+options.Margin, x-start, x-start)
+		}
+	}
+
+	_, err := fmt.Fprintf(w,
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="%[1]d" viewBox="0 0 %[2]d %[2]d" shape-rendering="crispEdges">`+
+			`<rect width="%[2]d" height="%[2]d" fill="%[3]s"/><path fill="%[4]s" d="%[5]s"/></svg>`,
+		options.Size, total, hex(options.Background), hex(options.Foreground), path.String())
+
+	return err
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
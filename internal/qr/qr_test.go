@@ -0,0 +1,157 @@
+package qr_test
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"shortener/internal/qr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePNG(t *testing.T) {
+	options := qr.DefaultOptions()
+	options.Size = 300
+	options.Foreground = color.RGBA{R: 0xff, A: 0xff}
+
+	buf := bytes.Buffer{}
+	require.NoError(t, qr.Write(&buf, "https://sho.rt/abc", options))
+
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, 300, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	r, g, b, _ := img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0xffff, 0xffff}, [3]uint32{r, g, b}, "quiet zone uses background")
+
+	options.Margin = 0
+	buf.Reset()
+	require.NoError(t, qr.Write(&buf, "https://sho.rt/abc", options))
+
+	img, err = png.Decode(&buf)
+	require.NoError(t, err)
+
+	r, g, b, _ = img.At(0, 0).RGBA()
+	assert.Equal(t, [3]uint32{0xffff, 0, 0}, [3]uint32{r, g, b}, "finder pattern uses foreground")
+}
+
+func TestWritePNGWholeModules(t *testing.T) {
+	options := qr.DefaultOptions()
+	options.Size = 100
+	options.Margin = 0
+
+	buf := bytes.Buffer{}
+	require.NoError(t, qr.Write(&buf, "https://sho.rt/abc", options))
+
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	require.Equal(t, 100, img.Bounds().Dx())
+
+	isDark := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r == 0
+	}
+
+	// the top left finder pattern starts where the centred code starts
+	start := 0
+	for start < 100 && !isDark(start, start) {
+		start++
+	}
+	require.Less(t, start, 50)
+
+	// every run of equal pixels through the finder row is a whole number of modules
+	runs := []int{}
+	run := 1
+	for x := start + 1; x < 100-start; x++ {
+		if isDark(x, start) == isDark(x-1, start) {
+			run++
+			continue
+		}
+		runs = append(runs, run)
+		run = 1
+	}
+	runs = append(runs, run)
+
+	scale := runs[0] / 7
+	require.Greater(t, scale, 1)
+	for _, r := range runs {
+		assert.Zero(t, r%scale, "run of %d pixels with %d pixel modules", r, scale)
+	}
+}
+
+func TestWriteTooSmall(t *testing.T) {
+	options := qr.DefaultOptions()
+	options.Size = qr.MinSize
+	options.Margin = qr.MaxMargin
+	options.Level = "H"
+
+	err := qr.Write(&bytes.Buffer{}, "https://sho.rt/"+strings.Repeat("a", 100), options)
+	assert.ErrorIs(t, err, qr.ErrInvalidOptions)
+}
+
+func TestWriteSVG(t *testing.T) {
+	options := qr.DefaultOptions()
+	options.Format = qr.FormatSVG
+	options.Background, _ = qr.ParseColor("#fafafa")
+
+	buf := bytes.Buffer{}
+	require.NoError(t, qr.Write(&buf, "https://sho.rt/abc", options))
+
+	svg := buf.String()
+	assert.True(t, strings.HasPrefix(svg, "<svg"))
+	assert.Contains(t, svg, `width="256"`)
+	assert.Contains(t, svg, `fill="#fafafa"`)
+	assert.Contains(t, svg, `fill="#000000"`)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(o *qr.Options)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(o *qr.Options) {}},
+		{name: "format", modify: func(o *qr.Options) { o.Format = "gif" }, wantErr: true},
+		{name: "size too small", modify: func(o *qr.Options) { o.Size = 10 }, wantErr: true},
+		{name: "size too large", modify: func(o *qr.Options) { o.Size = 5000 }, wantErr: true},
+		{name: "negative margin", modify: func(o *qr.Options) { o.Margin = -1 }, wantErr: true},
+		{name: "level", modify: func(o *qr.Options) { o.Level = "X" }, wantErr: true},
+		{name: "highest level", modify: func(o *qr.Options) { o.Level = "H" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := qr.DefaultOptions()
+			tt.modify(&options)
+
+			err := options.Validate()
+			if tt.wantErr {
+				assert.ErrorIs(t, err, qr.ErrInvalidOptions)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestParseColor(t *testing.T) {
+	c, err := qr.ParseColor("#1a2B3c")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 0x1a, G: 0x2b, B: 0x3c, A: 0xff}, c)
+
+	c, err = qr.ParseColor("f0a")
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBA{R: 0xff, A: 0xff, B: 0xaa}, c)
+
+	_, err = qr.ParseColor("red")
+	assert.ErrorIs(t, err, qr.ErrInvalidOptions)
+
+	_, err = qr.ParseColor("12345g")
+	assert.ErrorIs(t, err, qr.ErrInvalidOptions)
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+type failures struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+// Lockout blocks a key for a while after maxFailures failed attempts
+// made within that same period.
+type Lockout struct {
+	mu          sync.Mutex
+	maxFailures int
+	duration    time.Duration
+	keys        map[string]*failures
+	lastSweep   time.Time
+}
+
+func NewLockout(maxFailures int, duration time.Duration) *Lockout {
+	if maxFailures <= 0 {
+		maxFailures = 1
+	}
+
+	return &Lockout{
+		maxFailures: maxFailures,
+		duration:    duration,
+		keys:        make(map[string]*failures),
+		lastSweep:   time.Now(),
+	}
+}
+
+func (l *Lockout) Locked(key string) bool {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, ok := l.keys[key]
+
+	return ok && now.Before(f.lockedUntil)
+}
+
+func (l *Lockout) Fail(key string) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	f, ok := l.keys[key]
+	if !ok || l.stale(f, now) {
+		f = &failures{first: now}
+		l.keys[key] = f
+	}
+
+	f.count++
+	if f.count >= l.maxFailures {
+		f.lockedUntil = now.Add(l.duration)
+	}
+}
+
+func (l *Lockout) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.keys, key)
+}
+
+func (l *Lockout) stale(f *failures, now time.Time) bool {
+	if !f.lockedUntil.IsZero() {
+		return !now.Before(f.lockedUntil)
+	}
+
+	return now.Sub(f.first) >= l.duration
+}
+
+func (l *Lockout) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, f := range l.keys {
+		if l.stale(f, now) {
+			delete(l.keys, key)
+		}
+	}
+}
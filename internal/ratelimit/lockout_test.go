@@ -0,0 +1,44 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"shortener/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockout(t *testing.T) {
+	lockout := ratelimit.NewLockout(3, time.Minute)
+
+	lockout.Fail("code")
+	lockout.Fail("code")
+	assert.False(t, lockout.Locked("code"))
+
+	lockout.Fail("code")
+	assert.True(t, lockout.Locked("code"))
+	assert.False(t, lockout.Locked("other"))
+}
+
+func TestLockoutReset(t *testing.T) {
+	lockout := ratelimit.NewLockout(2, time.Minute)
+
+	lockout.Fail("code")
+	lockout.Reset("code")
+	lockout.Fail("code")
+	assert.False(t, lockout.Locked("code"))
+}
+
+func TestLockoutExpires(t *testing.T) {
+	lockout := ratelimit.NewLockout(1, 20*time.Millisecond)
+
+	lockout.Fail("code")
+	assert.True(t, lockout.Locked("code"))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, lockout.Locked("code"))
+
+	lockout.Fail("code")
+	assert.True(t, lockout.Locked("code"))
+}
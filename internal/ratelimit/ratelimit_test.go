@@ -0,0 +1,64 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"shortener/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1, 2)
+
+	first := limiter.Allow("client")
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 2, first.Limit)
+	assert.Equal(t, 1, first.Remaining)
+
+	second := limiter.Allow("client")
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+
+	third := limiter.Allow("client")
+	assert.False(t, third.Allowed)
+	assert.Equal(t, 0, third.Remaining)
+	assert.InDelta(t, time.Second, third.RetryAfter, float64(50*time.Millisecond))
+	assert.InDelta(t, 2*time.Second, third.Reset, float64(50*time.Millisecond))
+}
+
+func TestLimiterAllowN(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1, 5)
+
+	first := limiter.AllowN("client", 3)
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 2, first.Remaining)
+
+	second := limiter.AllowN("client", 3)
+	assert.False(t, second.Allowed)
+	assert.Equal(t, 2, second.Remaining)
+	assert.InDelta(t, time.Second, second.RetryAfter, float64(50*time.Millisecond))
+
+	assert.True(t, limiter.AllowN("client", 2).Allowed)
+	assert.False(t, limiter.AllowN("other", 6).Allowed)
+}
+
+func TestLimiterKeysAreIndependent(t *testing.T) {
+	limiter := ratelimit.NewLimiter(1, 1)
+
+	assert.True(t, limiter.Allow("a").Allowed)
+	assert.False(t, limiter.Allow("a").Allowed)
+	assert.True(t, limiter.Allow("b").Allowed)
+}
+
+func TestLimiterRefill(t *testing.T) {
+	limiter := ratelimit.NewLimiter(50, 1)
+
+	assert.True(t, limiter.Allow("client").Allowed)
+	assert.False(t, limiter.Allow("client").Allowed)
+
+	time.Sleep(30 * time.Millisecond)
+
+	assert.True(t, limiter.Allow("client").Allowed)
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"net"
+	"time"
+
+	grpchandlers "shortener/internal/controllers/grpc"
+	"shortener/internal/controllers/grpc/pb"
+	"shortener/pkg/logger"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+)
+
+type GRPCServer struct {
+	srv *grpc.Server
+	log logger.Logger
+}
+
+func NewGRPCServer(h *grpchandlers.Handlers, interceptors *grpchandlers.Interceptors, log logger.Logger) *GRPCServer {
+	srv := grpc.NewServer(
+		grpc.ConnectionTimeout(5*time.Second),
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(interceptors.Limit(), interceptors.RequireAPIKey()),
+	)
+
+	pb.RegisterShortenerServer(srv, h)
+
+	return &GRPCServer{
+		srv: srv,
+		log: log,
+	}
+}
+
+func (s *GRPCServer) Run(ctx context.Context, address string) error {
+	lis, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		s.log.Info("grpc server started",
+			logger.Field{Key: "address", Value: address},
+		)
+
+		if err := s.srv.Serve(lis); err != nil {
+			errCh <- err
+		}
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		s.log.Info("shutting down grpc server")
+
+		stopped := make(chan struct{})
+		go func() {
+			s.srv.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			s.srv.Stop()
+		}
+
+		return nil
+	}
+}
@@ -1,7 +1,10 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"net/http"
 	"time"
 
 	httphandlers "shortener/internal/controllers/http_handlers"
@@ -9,31 +12,99 @@ import (
 	"shortener/pkg/logger"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/valyala/fasthttp"
 )
 
+type timeouts struct {
+	read     time.Duration
+	write    time.Duration
+	idle     time.Duration
+	transfer time.Duration
+}
+
+// transferPrefix covers /api/admin/export and /api/admin/import, which move
+// the whole dataset and cannot finish within the usual request timeouts.
+var transferPrefix = []byte("/api/admin/")
+
+type Options struct {
+	// TransferTimeout replaces the read and write timeouts for the admin
+	// export and import routes.
+	TransferTimeout time.Duration
+	// ProxyHeader names the header with the client IP set by a reverse proxy;
+	// it is only trusted on connections from TrustedProxies.
+	ProxyHeader    string
+	TrustedProxies []string
+}
+
+var ErrNoTrustedProxies = errors.New("SERVICE_PROXY_HEADER requires SERVICE_TRUSTED_PROXIES")
+
 type Server struct {
 	app *fiber.App
 	log logger.Logger
 }
 
-func NewServer(h *httphandlers.ApiHandlers, log logger.Logger) *Server {
-	app := fiber.New(fiber.Config{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
-		IdleTimeout:  10 * time.Second,
-	})
+func NewServer(h *httphandlers.ApiHandlers, mw *middleware.Middleware, metrics http.Handler, options Options, log logger.Logger) (*Server, error) {
+	if options.ProxyHeader != "" && len(options.TrustedProxies) == 0 {
+		return nil, ErrNoTrustedProxies
+	}
+
+	app := newApp(timeouts{
+		read:     5 * time.Second,
+		write:    5 * time.Second,
+		idle:     10 * time.Second,
+		transfer: options.TransferTimeout,
+	}, options)
+
+	app.Use(mw.Trace(), mw.Observe())
 
 	addHealthCheck(app)
 
+	if metrics != nil {
+		app.Get("/metrics", adaptor.HTTPHandler(metrics))
+	}
+
 	api := app.Group("/api")
-	mw := middleware.NewMiddleware(log)
-	
 	h.MapApiRoutes(api, mw)
+	h.MapRedirectRoutes(app, mw)
 
 	return &Server{
 		app: app,
 		log: log,
+	}, nil
+}
+
+func newApp(t timeouts, options Options) *fiber.App {
+	app := fiber.New(fiber.Config{
+		ReadTimeout:  t.read,
+		WriteTimeout: t.write,
+		IdleTimeout:  t.idle,
+		// lets /api/admin/import read an export larger than the body limit;
+		// other routes are capped by middleware.LimitBody
+		StreamRequestBody: true,
+		// c.IP() falls back to the peer address when the header is absent,
+		// malformed or sent by anyone but a trusted proxy
+		ProxyHeader:             options.ProxyHeader,
+		EnableTrustedProxyCheck: options.ProxyHeader != "",
+		TrustedProxies:          options.TrustedProxies,
+		EnableIPValidation:      true,
+	})
+
+	// fasthttp applies the read deadline to the streamed request body and
+	// sets the write deadline once for the whole response, so a transfer
+	// would be cut off after the write timeout with a 200 already sent
+	app.Server().HeaderReceived = func(header *fasthttp.RequestHeader) fasthttp.RequestConfig {
+		if t.transfer <= 0 || !bytes.HasPrefix(header.RequestURI(), transferPrefix) {
+			return fasthttp.RequestConfig{}
+		}
+
+		return fasthttp.RequestConfig{
+			ReadTimeout:  t.transfer,
+			WriteTimeout: t.transfer,
+		}
 	}
+
+	return app
 }
 
 func addHealthCheck(app *fiber.App) {
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"shortener/pkg/logger"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	chunks    = 6
+	chunkSize = 64 * 1024
+	chunkGap  = 100 * time.Millisecond
+)
+
+func startApp(t *testing.T) string {
+	t.Helper()
+
+	app := newApp(timeouts{
+		read:     200 * time.Millisecond,
+		write:    200 * time.Millisecond,
+		idle:     time.Second,
+		transfer: 10 * time.Second,
+	}, Options{})
+
+	readBody := func(c *fiber.Ctx) error {
+		n, err := io.Copy(io.Discard, c.Context().RequestBodyStream())
+		if err != nil {
+			return err
+		}
+
+		return c.SendString(strconv.FormatInt(n, 10))
+	}
+
+	writeSlowly := func(c *fiber.Ctx) error {
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			chunk := bytes.Repeat([]byte("x"), chunkSize)
+			for range chunks {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+				if err := w.Flush(); err != nil {
+					return
+				}
+				time.Sleep(chunkGap)
+			}
+		})
+
+		return nil
+	}
+
+	app.Get("/api/admin/export", writeSlowly)
+	app.Post("/api/admin/import", readBody)
+	app.Get("/api/links", writeSlowly)
+	app.Post("/api/links", readBody)
+
+	return listen(t, app)
+}
+
+func listen(t *testing.T, app *fiber.App) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = app.Listener(ln) }()
+	t.Cleanup(func() { _ = app.Shutdown() })
+
+	return "http://" + ln.Addr().String()
+}
+
+// slowBody sends chunks with pauses, so the whole body takes longer than the
+// regular read timeout.
+func slowBody() io.Reader {
+	r, w := io.Pipe()
+
+	go func() {
+		chunk := bytes.Repeat([]byte("x"), chunkSize)
+		for range chunks {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			time.Sleep(chunkGap)
+		}
+		_ = w.Close()
+	}()
+
+	return r
+}
+
+func TestTransferTimeouts(t *testing.T) {
+	base := startApp(t)
+	total := strconv.Itoa(chunks * chunkSize)
+
+	t.Run("slow export", func(t *testing.T) {
+		resp, err := http.Get(base + "/api/admin/export")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, chunks*chunkSize, len(body))
+	})
+
+	t.Run("slow import", func(t *testing.T) {
+		resp, err := http.Post(base+"/api/admin/import", "application/x-ndjson", slowBody())
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, total, string(body))
+	})
+
+	t.Run("other routes keep the timeouts", func(t *testing.T) {
+		resp, err := http.Get(base + "/api/links")
+		if err == nil {
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.Less(t, len(body), chunks*chunkSize)
+		}
+
+		resp, err = http.Post(base+"/api/links", "application/json", slowBody())
+		if err == nil {
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			assert.NotEqual(t, total, string(body))
+		}
+	})
+}
+
+func TestProxyHeader(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted []string
+		header  string
+		want    string
+	}{
+		{name: "trusted proxy", trusted: []string{"127.0.0.0/8"}, header: "203.0.113.7", want: "203.0.113.7"},
+		{name: "untrusted peer", trusted: []string{"10.0.0.1"}, header: "203.0.113.7", want: "127.0.0.1"},
+		{name: "malformed header", trusted: []string{"127.0.0.1"}, header: "not an ip", want: "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newApp(timeouts{}, Options{ProxyHeader: "X-Real-IP", TrustedProxies: tt.trusted})
+			app.Get("/ip", func(c *fiber.Ctx) error {
+				return c.SendString(c.IP())
+			})
+
+			req, err := http.NewRequest(http.MethodGet, listen(t, app)+"/ip", nil)
+			require.NoError(t, err)
+			req.Header.Set("X-Real-IP", tt.header)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(body))
+		})
+	}
+}
+
+func TestProxyHeaderRequiresTrustedProxies(t *testing.T) {
+	_, err := NewServer(nil, nil, nil, Options{ProxyHeader: "X-Real-IP"}, logger.NewNop())
+	assert.ErrorIs(t, err, ErrNoTrustedProxies)
+}
@@ -0,0 +1,57 @@
+package sweeper
+
+import (
+	"context"
+	"time"
+
+	"shortener/pkg/logger"
+)
+
+type Repository interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type Sweeper struct {
+	repo     Repository
+	interval time.Duration
+	log      logger.Logger
+}
+
+func NewSweeper(repo Repository, interval time.Duration, log logger.Logger) *Sweeper {
+	return &Sweeper{
+		repo:     repo,
+		interval: interval,
+		log:      log,
+	}
+}
+
+func (s *Sweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *Sweeper) sweep(ctx context.Context) {
+	deleted, err := s.repo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			s.log.Error("expired links sweep failed",
+				logger.Field{Key: "error", Value: err})
+		}
+
+		return
+	}
+
+	if deleted > 0 {
+		s.log.Info("expired links purged",
+			logger.Field{Key: "count", Value: deleted})
+	}
+}
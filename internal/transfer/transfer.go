@@ -0,0 +1,411 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"shortener/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+
+	ConflictSkip      = "skip"
+	ConflictOverwrite = "overwrite"
+	ConflictFail      = "fail"
+)
+
+// Kinds of records that overwrite could not store because their url already
+// belongs to another code.
+const (
+	// KindNotCreated means the code is new, so there was nothing to replace.
+	KindNotCreated = "not_created"
+	// KindNotReplaced means the code exists but could not take the url.
+	KindNotReplaced = "not_replaced"
+)
+
+var (
+	ErrInvalidOptions = errors.New("invalid transfer options")
+	ErrInvalidRecord  = errors.New("invalid record")
+)
+
+var csvHeader = []string{"original", "shortened", "created_at", "expires_at", "password_hash", "protected"}
+
+type Source interface {
+	ForEachLink(ctx context.Context, fn func(link domain.Link) error) error
+}
+
+type Sink interface {
+	Save(ctx context.Context, link domain.Link) error
+	Replace(ctx context.Context, link domain.Link) error
+}
+
+type Validator interface {
+	ValidateURL(url string) (string, bool)
+	ValidateShortened(shortened string) bool
+	ValidateAlias(alias string) bool
+}
+
+// Policy decides whether a destination may be shortened; a nil Policy allows
+// every URL.
+type Policy interface {
+	Check(url string) error
+}
+
+type record struct {
+	Original     string     `json:"original"`
+	Shortened    string     `json:"shortened"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	PasswordHash string     `json:"password_hash,omitempty"`
+	Protected    bool       `json:"protected,omitempty"`
+}
+
+type ExportOptions struct {
+	Format string
+	// PasswordHashes adds the bcrypt hashes of protected links. Without them
+	// such links are only marked as protected and cannot be imported.
+	PasswordHashes bool
+}
+
+type ImportOptions struct {
+	Format   string
+	Conflict string
+}
+
+type ImportResult struct {
+	Created   int              `json:"created"`
+	Replaced  int              `json:"replaced"`
+	Skipped   int              `json:"skipped"`
+	Conflicts []ImportConflict `json:"conflicts,omitempty"`
+}
+
+// ImportConflict is a record left out by overwrite, the link already stored
+// under another code is kept.
+type ImportConflict struct {
+	Line      int    `json:"line"`
+	Shortened string `json:"shortened"`
+	Original  string `json:"original"`
+	Kind      string `json:"kind"`
+}
+
+func validFormat(format string) error {
+	if format != FormatJSONL && format != FormatCSV {
+		return fmt.Errorf("%w: format must be jsonl or csv", ErrInvalidOptions)
+	}
+
+	return nil
+}
+
+func (o ImportOptions) Validate() error {
+	if err := validFormat(o.Format); err != nil {
+		return err
+	}
+
+	switch o.Conflict {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+		return nil
+	default:
+		return fmt.Errorf("%w: conflict must be skip, overwrite or fail", ErrInvalidOptions)
+	}
+}
+
+// Export streams every link in the given format and returns how many were written.
+func Export(ctx context.Context, source Source, w io.Writer, options ExportOptions) (int, error) {
+	if err := validFormat(options.Format); err != nil {
+		return 0, err
+	}
+
+	write := newJSONLWriter(w)
+	flush := func() error { return nil }
+	if options.Format == FormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return 0, err
+		}
+
+		write = func(rec record) error { return cw.Write(csvRow(rec)) }
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+
+	var count int
+	err := source.ForEachLink(ctx, func(link domain.Link) error {
+		rec := record{
+			Original:  link.Original,
+			Shortened: link.Shortened,
+			CreatedAt: link.CreatedAt.UTC(),
+			ExpiresAt: link.ExpiresAt,
+			Protected: link.Protected(),
+		}
+
+		if options.PasswordHashes {
+			rec.PasswordHash = link.PasswordHash
+		}
+
+		if err := write(rec); err != nil {
+			return err
+		}
+
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, err
+	}
+
+	return count, flush()
+}
+
+// Import reads links written by Export and saves them one by one. Records
+// already imported stay in place when a later record fails.
+func Import(ctx context.Context, sink Sink, validator Validator, policy Policy, r io.Reader, options ImportOptions) (ImportResult, error) {
+	result := ImportResult{}
+
+	if err := options.Validate(); err != nil {
+		return result, err
+	}
+
+	next := newJSONLReader(r)
+	if options.Format == FormatCSV {
+		var err error
+		if next, err = newCSVReader(r); err != nil {
+			return result, err
+		}
+	}
+
+	for {
+		line, rec, err := next()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		link, err := toLink(rec, validator, policy)
+		if err != nil {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		err = sink.Save(ctx, link)
+		if err == nil {
+			result.Created++
+			continue
+		}
+
+		if !errors.Is(err, domain.ErrAlreadyExist) {
+			return result, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		switch options.Conflict {
+		case ConflictSkip:
+			result.Skipped++
+		case ConflictFail:
+			// the sink does not tell which of the two is taken
+			return result, fmt.Errorf("line %d: code %q or url %q: %w", line, link.Shortened, link.Original, err)
+		case ConflictOverwrite:
+			err = sink.Replace(ctx, link)
+			switch {
+			case err == nil:
+				result.Replaced++
+			case errors.Is(err, domain.ErrNotFound):
+				result.Conflicts = append(result.Conflicts, conflict(line, link, KindNotCreated))
+			case errors.Is(err, domain.ErrAlreadyExist):
+				result.Conflicts = append(result.Conflicts, conflict(line, link, KindNotReplaced))
+			default:
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+		}
+	}
+}
+
+func conflict(line int, link domain.Link, kind string) ImportConflict {
+	return ImportConflict{
+		Line:      line,
+		Shortened: link.Shortened,
+		Original:  link.Original,
+		Kind:      kind,
+	}
+}
+
+func toLink(rec record, validator Validator, policy Policy) (domain.Link, error) {
+	if rec.Original == "" || rec.Shortened == "" {
+		return domain.Link{}, fmt.Errorf("%w: original and shortened are required", ErrInvalidRecord)
+	}
+
+	if !validator.ValidateShortened(rec.Shortened) && !validator.ValidateAlias(rec.Shortened) {
+		return domain.Link{}, fmt.Errorf("%w: code %q", ErrInvalidRecord, rec.Shortened)
+	}
+
+	// importing it without the hash would make a protected link public
+	if rec.Protected && rec.PasswordHash == "" {
+		return domain.Link{}, fmt.Errorf("%w: code %q is password protected but has no password_hash", ErrInvalidRecord, rec.Shortened)
+	}
+
+	// a malformed hash would lock the link for good, no password matches it
+	if rec.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(rec.PasswordHash)); err != nil {
+			return domain.Link{}, fmt.Errorf("%w: code %q has an invalid password_hash: %v", ErrInvalidRecord, rec.Shortened, err)
+		}
+	}
+
+	original, ok := validator.ValidateURL(rec.Original)
+	if !ok {
+		return domain.Link{}, fmt.Errorf("%w: url %q", ErrInvalidRecord, rec.Original)
+	}
+
+	if policy != nil {
+		if err := policy.Check(original); err != nil {
+			return domain.Link{}, fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+		}
+	}
+
+	return domain.Link{
+		Original:     original,
+		Shortened:    rec.Shortened,
+		CreatedAt:    rec.CreatedAt,
+		ExpiresAt:    rec.ExpiresAt,
+		PasswordHash: rec.PasswordHash,
+	}, nil
+}
+
+func newJSONLWriter(w io.Writer) func(rec record) error {
+	enc := json.NewEncoder(w)
+
+	return func(rec record) error {
+		return enc.Encode(rec)
+	}
+}
+
+func newJSONLReader(r io.Reader) func() (int, record, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	line := 0
+	return func() (int, record, error) {
+		for scanner.Scan() {
+			line++
+
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+
+			rec := record{}
+			if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+				return line, rec, fmt.Errorf("line %d: %w: %v", line, ErrInvalidRecord, err)
+			}
+
+			return line, rec, nil
+		}
+
+		if err := scanner.Err(); err != nil {
+			return line, record{}, err
+		}
+
+		return line, record{}, io.EOF
+	}
+}
+
+func csvRow(rec record) []string {
+	row := []string{rec.Original, rec.Shortened, rec.CreatedAt.Format(time.RFC3339Nano), "", rec.PasswordHash, ""}
+	if rec.ExpiresAt != nil {
+		row[3] = rec.ExpiresAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	if rec.Protected {
+		row[5] = "true"
+	}
+
+	return row
+}
+
+// newCSVReader maps columns by the header row, so their order is free and
+// unknown columns are ignored.
+func newCSVReader(r io.Reader) (func() (int, record, error), error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = fmt.Errorf("%w: missing csv header", ErrInvalidRecord)
+		}
+
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	for _, name := range []string{"original", "shortened"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: csv header has no %q column", ErrInvalidRecord, name)
+		}
+	}
+
+	field := func(row []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return ""
+		}
+
+		return row[i]
+	}
+
+	return func() (int, record, error) {
+		row, err := cr.Read()
+		line, _ := cr.FieldPos(0)
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				return parseErr.Line, record{}, fmt.Errorf("line %d: %w: %v", parseErr.Line, ErrInvalidRecord, parseErr.Err)
+			}
+
+			return line, record{}, err
+		}
+
+		rec := record{
+			Original:     field(row, "original"),
+			Shortened:    field(row, "shortened"),
+			PasswordHash: field(row, "password_hash"),
+		}
+
+		if v := field(row, "created_at"); v != "" {
+			if rec.CreatedAt, err = time.Parse(time.RFC3339Nano, v); err != nil {
+				return line, rec, fmt.Errorf("line %d: %w: created_at: %v", line, ErrInvalidRecord, err)
+			}
+		}
+
+		if v := field(row, "protected"); v != "" {
+			if rec.Protected, err = strconv.ParseBool(v); err != nil {
+				return line, rec, fmt.Errorf("line %d: %w: protected: %v", line, ErrInvalidRecord, err)
+			}
+		}
+
+		if v := field(row, "expires_at"); v != "" {
+			expiresAt, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				return line, rec, fmt.Errorf("line %d: %w: expires_at: %v", line, ErrInvalidRecord, err)
+			}
+
+			rec.ExpiresAt = &expiresAt
+		}
+
+		return line, rec, nil
+	}, nil
+}
@@ -0,0 +1,304 @@
+package transfer_test
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"shortener/internal/domain"
+	"shortener/internal/transfer"
+	"shortener/internal/validator"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type fakeStore struct {
+	links []domain.Link
+}
+
+func (s *fakeStore) ForEachLink(_ context.Context, fn func(link domain.Link) error) error {
+	for _, link := range s.links {
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *fakeStore) Save(_ context.Context, link domain.Link) error {
+	for _, existing := range s.links {
+		if existing.Shortened == link.Shortened || existing.Original == link.Original {
+			return domain.ErrAlreadyExist
+		}
+	}
+
+	s.links = append(s.links, link)
+	return nil
+}
+
+func (s *fakeStore) Replace(_ context.Context, link domain.Link) error {
+	index := slices.IndexFunc(s.links, func(existing domain.Link) bool { return existing.Shortened == link.Shortened })
+	if index < 0 {
+		return domain.ErrNotFound
+	}
+
+	for _, existing := range s.links {
+		if existing.Shortened != link.Shortened && existing.Original == link.Original {
+			return domain.ErrAlreadyExist
+		}
+	}
+
+	s.links[index] = link
+	return nil
+}
+
+type denyHost string
+
+func (h denyHost) Check(url string) error {
+	if strings.Contains(url, string(h)) {
+		return domain.ErrForbiddenDestination
+	}
+
+	return nil
+}
+
+func newValidator(t *testing.T) *validator.Validator {
+	v, err := validator.NewValidator("abcdefgh", 8)
+	require.NoError(t, err)
+
+	return v
+}
+
+func TestExportImport(t *testing.T) {
+	createdAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	expiresAt := createdAt.Add(time.Hour)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, format := range []string{transfer.FormatJSONL, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			source := &fakeStore{links: []domain.Link{
+				{Original: "http://example.com/a", Shortened: "aaaaaaaa", CreatedAt: createdAt},
+				{Original: "http://example.com/b", Shortened: "bbbbbbbb", CreatedAt: createdAt, ExpiresAt: &expiresAt},
+				{Original: "http://example.com/c", Shortened: "my-alias", CreatedAt: createdAt, PasswordHash: string(hash)},
+			}}
+
+			var buf bytes.Buffer
+			count, err := transfer.Export(context.Background(), source, &buf, transfer.ExportOptions{
+				Format:         format,
+				PasswordHashes: true,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 3, count)
+
+			sink := &fakeStore{}
+			result, err := transfer.Import(context.Background(), sink, newValidator(t), nil, &buf, transfer.ImportOptions{
+				Format:   format,
+				Conflict: transfer.ConflictFail,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, transfer.ImportResult{Created: 3}, result)
+
+			require.Len(t, sink.links, 3)
+			for i, link := range sink.links {
+				want := source.links[i]
+				assert.Equal(t, want.Original, link.Original)
+				assert.Equal(t, want.Shortened, link.Shortened)
+				assert.True(t, want.CreatedAt.Equal(link.CreatedAt))
+				assert.Equal(t, want.PasswordHash, link.PasswordHash)
+				if want.ExpiresAt == nil {
+					assert.Nil(t, link.ExpiresAt)
+				} else {
+					require.NotNil(t, link.ExpiresAt)
+					assert.True(t, want.ExpiresAt.Equal(*link.ExpiresAt))
+				}
+			}
+		})
+	}
+}
+
+func TestExportWithoutPasswordHashes(t *testing.T) {
+	for _, format := range []string{transfer.FormatJSONL, transfer.FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			source := &fakeStore{links: []domain.Link{
+				{Original: "http://example.com/c", Shortened: "my-alias", CreatedAt: time.Now(), PasswordHash: "$2a$10$hash"},
+			}}
+
+			var buf bytes.Buffer
+			_, err := transfer.Export(context.Background(), source, &buf, transfer.ExportOptions{Format: format})
+			require.NoError(t, err)
+			assert.NotContains(t, buf.String(), "$2a$10$hash")
+
+			sink := &fakeStore{}
+			_, err = transfer.Import(context.Background(), sink, newValidator(t), nil, &buf, transfer.ImportOptions{
+				Format:   format,
+				Conflict: transfer.ConflictFail,
+			})
+			require.ErrorIs(t, err, transfer.ErrInvalidRecord)
+			assert.Contains(t, err.Error(), "password protected")
+			assert.Empty(t, sink.links)
+		})
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	input := `{"original":"http://example.com/new","shortened":"aaaaaaaa"}
+{"original":"http://example.com/b","shortened":"cccccccc"}
+{"original":"http://example.com/c","shortened":"dddddddd"}
+{"original":"http://example.com/b","shortened":"dddddddd"}
+`
+
+	tests := []struct {
+		name     string
+		conflict string
+		result   transfer.ImportResult
+		err      error
+		original string
+	}{
+		{
+			name:     "skip",
+			conflict: transfer.ConflictSkip,
+			result:   transfer.ImportResult{Created: 1, Skipped: 3},
+			original: "http://example.com/a",
+		},
+		{
+			name:     "overwrite",
+			conflict: transfer.ConflictOverwrite,
+			result: transfer.ImportResult{Created: 1, Replaced: 1, Conflicts: []transfer.ImportConflict{
+				{Line: 2, Shortened: "cccccccc", Original: "http://example.com/b", Kind: transfer.KindNotCreated},
+				{Line: 4, Shortened: "dddddddd", Original: "http://example.com/b", Kind: transfer.KindNotReplaced},
+			}},
+			original: "http://example.com/new",
+		},
+		{
+			name:     "fail",
+			conflict: transfer.ConflictFail,
+			err:      domain.ErrAlreadyExist,
+			original: "http://example.com/a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := &fakeStore{links: []domain.Link{
+				{Original: "http://example.com/a", Shortened: "aaaaaaaa"},
+				{Original: "http://example.com/b", Shortened: "bbbbbbbb"},
+			}}
+
+			result, err := transfer.Import(context.Background(), sink, newValidator(t), nil, strings.NewReader(input), transfer.ImportOptions{
+				Format:   transfer.FormatJSONL,
+				Conflict: tt.conflict,
+			})
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				assert.Contains(t, err.Error(), "line 1")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, tt.result, result)
+			}
+
+			assert.Equal(t, tt.original, sink.links[0].Original)
+		})
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options transfer.ImportOptions
+		input   string
+		err     error
+		msg     string
+	}{
+		{
+			name:    "unknown format",
+			options: transfer.ImportOptions{Format: "xml", Conflict: transfer.ConflictSkip},
+			err:     transfer.ErrInvalidOptions,
+		},
+		{
+			name:    "unknown conflict policy",
+			options: transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: "merge"},
+			err:     transfer.ErrInvalidOptions,
+		},
+		{
+			name:    "malformed json",
+			options: transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: transfer.ConflictSkip},
+			input:   "{\"original\":\"http://example.com\",\"shortened\":\"aaaaaaaa\"}\n\n{oops}\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     "line 3",
+		},
+		{
+			name:    "missing shortened",
+			options: transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: transfer.ConflictSkip},
+			input:   "{\"original\":\"http://example.com\"}\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     "line 1",
+		},
+		{
+			name:    "invalid code",
+			options: transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: transfer.ConflictSkip},
+			input:   "{\"original\":\"http://example.com\",\"shortened\":\"zz\"}\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     `code "zz"`,
+		},
+		{
+			name:    "invalid url",
+			options: transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: transfer.ConflictSkip},
+			input:   "{\"original\":\"ftp://example.com\",\"shortened\":\"aaaaaaaa\"}\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     "url",
+		},
+		{
+			name:    "invalid password hash",
+			options: transfer.ImportOptions{Format: transfer.FormatJSONL, Conflict: transfer.ConflictSkip},
+			input:   "{\"original\":\"http://example.com\",\"shortened\":\"aaaaaaaa\",\"password_hash\":\"secret\",\"protected\":true}\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     "password_hash",
+		},
+		{
+			name:    "csv without header",
+			options: transfer.ImportOptions{Format: transfer.FormatCSV, Conflict: transfer.ConflictSkip},
+			input:   "http://example.com,aaaaaaaa\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     "csv header",
+		},
+		{
+			name:    "csv bad timestamp",
+			options: transfer.ImportOptions{Format: transfer.FormatCSV, Conflict: transfer.ConflictSkip},
+			input:   "shortened,original,created_at\naaaaaaaa,http://example.com,yesterday\n",
+			err:     transfer.ErrInvalidRecord,
+			msg:     "line 2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := transfer.Import(context.Background(), &fakeStore{}, newValidator(t), nil, strings.NewReader(tt.input), tt.options)
+			require.ErrorIs(t, err, tt.err)
+			assert.Contains(t, err.Error(), tt.msg)
+		})
+	}
+}
+
+func TestImportPolicy(t *testing.T) {
+	input := `{"original":"http://example.com/a","shortened":"aaaaaaaa"}
+{"original":"http://internal.test/b","shortened":"bbbbbbbb"}
+`
+
+	sink := &fakeStore{}
+	result, err := transfer.Import(context.Background(), sink, newValidator(t), denyHost("internal.test"), strings.NewReader(input), transfer.ImportOptions{
+		Format:   transfer.FormatJSONL,
+		Conflict: transfer.ConflictSkip,
+	})
+	require.ErrorIs(t, err, domain.ErrForbiddenDestination)
+	require.ErrorIs(t, err, transfer.ErrInvalidRecord)
+	assert.Contains(t, err.Error(), "line 2")
+	assert.Equal(t, transfer.ImportResult{Created: 1}, result)
+}
@@ -7,6 +7,8 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	domain "shortener/internal/domain"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 )
@@ -34,11 +36,54 @@ func (m *MockRepository) EXPECT() *MockRepositoryMockRecorder {
 	return m.recorder
 }
 
+// Delete mocks base method.
+func (m *MockRepository) Delete(ctx context.Context, shortened string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", ctx, shortened)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockRepositoryMockRecorder) Delete(ctx, shortened interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRepository)(nil).Delete), ctx, shortened)
+}
+
+// DeleteExpired mocks base method.
+func (m *MockRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteExpired", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteExpired indicates an expected call of DeleteExpired.
+func (mr *MockRepositoryMockRecorder) DeleteExpired(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteExpired", reflect.TypeOf((*MockRepository)(nil).DeleteExpired), ctx, before)
+}
+
+// ForEachLink mocks base method.
+func (m *MockRepository) ForEachLink(ctx context.Context, fn func(domain.Link) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForEachLink", ctx, fn)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ForEachLink indicates an expected call of ForEachLink.
+func (mr *MockRepositoryMockRecorder) ForEachLink(ctx, fn interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForEachLink", reflect.TypeOf((*MockRepository)(nil).ForEachLink), ctx, fn)
+}
+
 // GetByOriginal mocks base method.
-func (m *MockRepository) GetByOriginal(ctx context.Context, original string) (string, error) {
+func (m *MockRepository) GetByOriginal(ctx context.Context, original string) (domain.Link, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetByOriginal", ctx, original)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(domain.Link)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -50,10 +95,10 @@ func (mr *MockRepositoryMockRecorder) GetByOriginal(ctx, original interface{}) *
 }
 
 // GetByShortened mocks base method.
-func (m *MockRepository) GetByShortened(ctx context.Context, short string) (string, error) {
+func (m *MockRepository) GetByShortened(ctx context.Context, short string) (domain.Link, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetByShortened", ctx, short)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(domain.Link)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -64,18 +109,91 @@ func (mr *MockRepositoryMockRecorder) GetByShortened(ctx, short interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByShortened", reflect.TypeOf((*MockRepository)(nil).GetByShortened), ctx, short)
 }
 
+// GetClickStats mocks base method.
+func (m *MockRepository) GetClickStats(ctx context.Context, shortened string) (domain.ClickStats, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClickStats", ctx, shortened)
+	ret0, _ := ret[0].(domain.ClickStats)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetClickStats indicates an expected call of GetClickStats.
+func (mr *MockRepositoryMockRecorder) GetClickStats(ctx, shortened interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClickStats", reflect.TypeOf((*MockRepository)(nil).GetClickStats), ctx, shortened)
+}
+
+// List mocks base method.
+func (m *MockRepository) List(ctx context.Context, filter domain.ListFilter) (domain.LinkPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, filter)
+	ret0, _ := ret[0].(domain.LinkPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockRepositoryMockRecorder) List(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockRepository)(nil).List), ctx, filter)
+}
+
+// Replace mocks base method.
+func (m *MockRepository) Replace(ctx context.Context, link domain.Link) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Replace", ctx, link)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Replace indicates an expected call of Replace.
+func (mr *MockRepositoryMockRecorder) Replace(ctx, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Replace", reflect.TypeOf((*MockRepository)(nil).Replace), ctx, link)
+}
+
 // Save mocks base method.
-func (m *MockRepository) Save(ctx context.Context, original, short string) error {
+func (m *MockRepository) Save(ctx context.Context, link domain.Link) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", ctx, original, short)
+	ret := m.ctrl.Call(m, "Save", ctx, link)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockRepositoryMockRecorder) Save(ctx, original, short interface{}) *gomock.Call {
+func (mr *MockRepositoryMockRecorder) Save(ctx, link interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRepository)(nil).Save), ctx, link)
+}
+
+// SaveBatch mocks base method.
+func (m *MockRepository) SaveBatch(ctx context.Context, links []domain.Link) ([]error, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveBatch", ctx, links)
+	ret0, _ := ret[0].([]error)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveBatch indicates an expected call of SaveBatch.
+func (mr *MockRepositoryMockRecorder) SaveBatch(ctx, links interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveBatch", reflect.TypeOf((*MockRepository)(nil).SaveBatch), ctx, links)
+}
+
+// UpdateOriginal mocks base method.
+func (m *MockRepository) UpdateOriginal(ctx context.Context, shortened, original string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOriginal", ctx, shortened, original)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOriginal indicates an expected call of UpdateOriginal.
+func (mr *MockRepositoryMockRecorder) UpdateOriginal(ctx, shortened, original interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRepository)(nil).Save), ctx, original, short)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOriginal", reflect.TypeOf((*MockRepository)(nil).UpdateOriginal), ctx, shortened, original)
 }
 
 // MockGenerator is a mock of Generator interface.
@@ -102,18 +220,18 @@ func (m *MockGenerator) EXPECT() *MockGeneratorMockRecorder {
 }
 
 // Generate mocks base method.
-func (m *MockGenerator) Generate() (string, error) {
+func (m *MockGenerator) Generate(ctx context.Context, url string, attempt int) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Generate")
+	ret := m.ctrl.Call(m, "Generate", ctx, url, attempt)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Generate indicates an expected call of Generate.
-func (mr *MockGeneratorMockRecorder) Generate() *gomock.Call {
+func (mr *MockGeneratorMockRecorder) Generate(ctx, url, attempt interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Generate", reflect.TypeOf((*MockGenerator)(nil).Generate))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Generate", reflect.TypeOf((*MockGenerator)(nil).Generate), ctx, url, attempt)
 }
 
 // MockValidator is a mock of Validator interface.
@@ -139,6 +257,34 @@ func (m *MockValidator) EXPECT() *MockValidatorMockRecorder {
 	return m.recorder
 }
 
+// IsReserved mocks base method.
+func (m *MockValidator) IsReserved(code string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsReserved", code)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// IsReserved indicates an expected call of IsReserved.
+func (mr *MockValidatorMockRecorder) IsReserved(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsReserved", reflect.TypeOf((*MockValidator)(nil).IsReserved), code)
+}
+
+// ValidateAlias mocks base method.
+func (m *MockValidator) ValidateAlias(alias string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ValidateAlias", alias)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// ValidateAlias indicates an expected call of ValidateAlias.
+func (mr *MockValidatorMockRecorder) ValidateAlias(alias interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateAlias", reflect.TypeOf((*MockValidator)(nil).ValidateAlias), alias)
+}
+
 // ValidateShortened mocks base method.
 func (m *MockValidator) ValidateShortened(shortened string) bool {
 	m.ctrl.T.Helper()
@@ -167,3 +313,207 @@ func (mr *MockValidatorMockRecorder) ValidateURL(url interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ValidateURL", reflect.TypeOf((*MockValidator)(nil).ValidateURL), url)
 }
+
+// MockPolicy is a mock of Policy interface.
+type MockPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockPolicyMockRecorder
+}
+
+// MockPolicyMockRecorder is the mock recorder for MockPolicy.
+type MockPolicyMockRecorder struct {
+	mock *MockPolicy
+}
+
+// NewMockPolicy creates a new mock instance.
+func NewMockPolicy(ctrl *gomock.Controller) *MockPolicy {
+	mock := &MockPolicy{ctrl: ctrl}
+	mock.recorder = &MockPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPolicy) EXPECT() *MockPolicyMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockPolicy) Check(url string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", url)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockPolicyMockRecorder) Check(url interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockPolicy)(nil).Check), url)
+}
+
+// MockLockout is a mock of Lockout interface.
+type MockLockout struct {
+	ctrl     *gomock.Controller
+	recorder *MockLockoutMockRecorder
+}
+
+// MockLockoutMockRecorder is the mock recorder for MockLockout.
+type MockLockoutMockRecorder struct {
+	mock *MockLockout
+}
+
+// NewMockLockout creates a new mock instance.
+func NewMockLockout(ctrl *gomock.Controller) *MockLockout {
+	mock := &MockLockout{ctrl: ctrl}
+	mock.recorder = &MockLockoutMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLockout) EXPECT() *MockLockoutMockRecorder {
+	return m.recorder
+}
+
+// Fail mocks base method.
+func (m *MockLockout) Fail(key string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Fail", key)
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLockoutMockRecorder) Fail(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLockout)(nil).Fail), key)
+}
+
+// Locked mocks base method.
+func (m *MockLockout) Locked(key string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Locked", key)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// Locked indicates an expected call of Locked.
+func (mr *MockLockoutMockRecorder) Locked(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Locked", reflect.TypeOf((*MockLockout)(nil).Locked), key)
+}
+
+// Reset mocks base method.
+func (m *MockLockout) Reset(key string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Reset", key)
+}
+
+// Reset indicates an expected call of Reset.
+func (mr *MockLockoutMockRecorder) Reset(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reset", reflect.TypeOf((*MockLockout)(nil).Reset), key)
+}
+
+// MockClickRecorder is a mock of ClickRecorder interface.
+type MockClickRecorder struct {
+	ctrl     *gomock.Controller
+	recorder *MockClickRecorderMockRecorder
+}
+
+// MockClickRecorderMockRecorder is the mock recorder for MockClickRecorder.
+type MockClickRecorderMockRecorder struct {
+	mock *MockClickRecorder
+}
+
+// NewMockClickRecorder creates a new mock instance.
+func NewMockClickRecorder(ctrl *gomock.Controller) *MockClickRecorder {
+	mock := &MockClickRecorder{ctrl: ctrl}
+	mock.recorder = &MockClickRecorderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClickRecorder) EXPECT() *MockClickRecorderMockRecorder {
+	return m.recorder
+}
+
+// Record mocks base method.
+func (m *MockClickRecorder) Record(click domain.Click) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Record", click)
+}
+
+// Record indicates an expected call of Record.
+func (mr *MockClickRecorderMockRecorder) Record(click interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Record", reflect.TypeOf((*MockClickRecorder)(nil).Record), click)
+}
+
+// MockMetrics is a mock of Metrics interface.
+type MockMetrics struct {
+	ctrl     *gomock.Controller
+	recorder *MockMetricsMockRecorder
+}
+
+// MockMetricsMockRecorder is the mock recorder for MockMetrics.
+type MockMetricsMockRecorder struct {
+	mock *MockMetrics
+}
+
+// NewMockMetrics creates a new mock instance.
+func NewMockMetrics(ctrl *gomock.Controller) *MockMetrics {
+	mock := &MockMetrics{ctrl: ctrl}
+	mock.recorder = &MockMetricsMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMetrics) EXPECT() *MockMetricsMockRecorder {
+	return m.recorder
+}
+
+// CollisionRetry mocks base method.
+func (m *MockMetrics) CollisionRetry() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CollisionRetry")
+}
+
+// CollisionRetry indicates an expected call of CollisionRetry.
+func (mr *MockMetricsMockRecorder) CollisionRetry() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollisionRetry", reflect.TypeOf((*MockMetrics)(nil).CollisionRetry))
+}
+
+// DedupHit mocks base method.
+func (m *MockMetrics) DedupHit() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DedupHit")
+}
+
+// DedupHit indicates an expected call of DedupHit.
+func (mr *MockMetricsMockRecorder) DedupHit() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DedupHit", reflect.TypeOf((*MockMetrics)(nil).DedupHit))
+}
+
+// LinkCreated mocks base method.
+func (m *MockMetrics) LinkCreated() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "LinkCreated")
+}
+
+// LinkCreated indicates an expected call of LinkCreated.
+func (mr *MockMetricsMockRecorder) LinkCreated() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LinkCreated", reflect.TypeOf((*MockMetrics)(nil).LinkCreated))
+}
+
+// MaxAttemptsExceeded mocks base method.
+func (m *MockMetrics) MaxAttemptsExceeded() {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "MaxAttemptsExceeded")
+}
+
+// MaxAttemptsExceeded indicates an expected call of MaxAttemptsExceeded.
+func (mr *MockMetricsMockRecorder) MaxAttemptsExceeded() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxAttemptsExceeded", reflect.TypeOf((*MockMetrics)(nil).MaxAttemptsExceeded))
+}
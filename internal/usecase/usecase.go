@@ -3,37 +3,106 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"shortener/internal/domain"
+	"shortener/internal/tracing"
+	"shortener/internal/transfer"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/idna"
 )
 
+var tracer = otel.Tracer("shortener/internal/usecase")
+
 type Repository interface {
-	Save(ctx context.Context, original, short string) error
-	GetByShortened(ctx context.Context, short string) (string, error)
-	GetByOriginal(ctx context.Context, original string) (string, error)
+	Save(ctx context.Context, link domain.Link) error
+	SaveBatch(ctx context.Context, links []domain.Link) ([]error, error)
+	GetByShortened(ctx context.Context, short string) (domain.Link, error)
+	GetByOriginal(ctx context.Context, original string) (domain.Link, error)
+	Delete(ctx context.Context, shortened string) error
+	UpdateOriginal(ctx context.Context, shortened, original string) error
+	Replace(ctx context.Context, link domain.Link) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+	GetClickStats(ctx context.Context, shortened string) (domain.ClickStats, error)
+	ForEachLink(ctx context.Context, fn func(link domain.Link) error) error
+	List(ctx context.Context, filter domain.ListFilter) (domain.LinkPage, error)
 }
 
 type Generator interface {
-	Generate() (string, error)
+	Generate(ctx context.Context, url string, attempt int) (string, error)
 }
 
 type Validator interface {
 	ValidateURL(url string) (string, bool)
 	ValidateShortened(shortened string) bool
+	ValidateAlias(alias string) bool
+	IsReserved(code string) bool
+}
+
+type Policy interface {
+	Check(url string) error
+}
+
+type Lockout interface {
+	Locked(key string) bool
+	Fail(key string)
+	Reset(key string)
+}
+
+var errMaxAttemptsExceeded = errors.New("maxAttempts exceeded")
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+)
+
+type ClickRecorder interface {
+	Record(click domain.Click)
+}
+
+type Metrics interface {
+	LinkCreated()
+	DedupHit()
+	CollisionRetry()
+	MaxAttemptsExceeded()
 }
 
+type nopMetrics struct{}
+
+func (nopMetrics) LinkCreated()         {}
+func (nopMetrics) DedupHit()            {}
+func (nopMetrics) CollisionRetry()      {}
+func (nopMetrics) MaxAttemptsExceeded() {}
+
 type UsecaseOptions struct {
-	Repository  Repository
-	Generator   Generator
-	Validator   Validator
-	MaxAttempts int
-	Protection  bool
+	Repository   Repository
+	Generator    Generator
+	Validator    Validator
+	Clicks       ClickRecorder
+	Metrics      Metrics
+	Policy       Policy
+	Lockout      Lockout
+	MaxAttempts  int
+	MaxBatchSize int
+	Protection   bool
 }
 
 type Usecase struct {
 	repo        Repository
 	gen         Generator
 	validator   Validator
+	clicks      ClickRecorder
+	metrics     Metrics
+	policy      Policy
+	lockout     Lockout
 	maxAttempts int
+	maxBatch    int
 	protec      bool
 }
 
@@ -41,69 +110,563 @@ func NewUsecase(options UsecaseOptions) (*Usecase, error) {
 	if options.MaxAttempts <= 0 {
 		return nil, errors.New("maxAttempts must be positive")
 	}
+
+	if options.Metrics == nil {
+		options.Metrics = nopMetrics{}
+	}
+
 	return &Usecase{
 		repo:        options.Repository,
 		gen:         options.Generator,
 		validator:   options.Validator,
+		clicks:      options.Clicks,
+		metrics:     options.Metrics,
+		policy:      options.Policy,
+		lockout:     options.Lockout,
 		maxAttempts: options.MaxAttempts,
+		maxBatch:    options.MaxBatchSize,
 		protec:      options.Protection,
 	}, nil
 }
 
-func (uc *Usecase) CreateShortened(ctx context.Context, url string) (string, error) {
+func (uc *Usecase) CreateShortened(ctx context.Context, params domain.CreateParams) (_ domain.Link, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CreateShortened")
+	defer func() { tracing.End(span, err) }()
+
+	url := params.URL
 	if uc.protec {
 		ok := false
 		url, ok = uc.validator.ValidateURL(url)
 		if !ok {
-			return "", domain.ErrInvalidURL
+			return domain.Link{}, domain.ErrInvalidURL
 		}
 	}
 
-	for range uc.maxAttempts {
-		shortened, err := uc.repo.GetByOriginal(ctx, url)
-		if err == nil {
-			return shortened, nil
-		}
+	if err := uc.checkPolicy(url); err != nil {
+		return domain.Link{}, err
+	}
 
-		if !errors.Is(err, domain.ErrNotFound) {
-			return "", err
+	expiresAt, err := expiration(params)
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	passwordHash, err := hashPassword(params.Password)
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	if params.Alias != "" {
+		return uc.createWithAlias(ctx, domain.Link{
+			Original:     url,
+			Shortened:    params.Alias,
+			ExpiresAt:    expiresAt,
+			PasswordHash: passwordHash,
+		})
+	}
+
+	// only public links without an expiry are shared between requests,
+	// anything else always gets a code of its own
+	reusable := domain.Link{ExpiresAt: expiresAt, PasswordHash: passwordHash}.Reusable()
+
+	for attempt := range uc.maxAttempts {
+		if reusable {
+			link, err := uc.repo.GetByOriginal(ctx, url)
+			if err == nil {
+				uc.metrics.DedupHit()
+				return link, nil
+			}
+
+			if !errors.Is(err, domain.ErrNotFound) {
+				return domain.Link{}, err
+			}
 		}
 
-		shortened, err = uc.gen.Generate()
+		shortened, err := uc.gen.Generate(ctx, url, attempt)
 		if err != nil {
-			return "", err
+			return domain.Link{}, err
+		}
+
+		// a code like "api" would be shadowed by the service's own routes
+		if uc.validator.IsReserved(shortened) {
+			uc.metrics.CollisionRetry()
+			continue
 		}
 
-		err = uc.repo.Save(ctx, url, shortened)
+		link := domain.Link{
+			Original:     url,
+			Shortened:    shortened,
+			ExpiresAt:    expiresAt,
+			PasswordHash: passwordHash,
+		}
+
+		err = uc.repo.Save(ctx, link)
 		if err != nil {
 			if errors.Is(err, domain.ErrAlreadyExist) {
+				uc.metrics.CollisionRetry()
 				continue
 			}
 
-			return "", err
+			return domain.Link{}, err
 		}
 
-		return shortened, nil
+		uc.metrics.LinkCreated()
+		return link, nil
 	}
 
-	return "", errors.New("maxAttempts exceeded")
+	uc.metrics.MaxAttemptsExceeded()
+	return domain.Link{}, errMaxAttemptsExceeded
 }
 
-func (uc *Usecase) GetOriginalByShortened(ctx context.Context, shortened string) (string, error) {
-	if uc.protec {
-		if !uc.validator.ValidateShortened(shortened) {
-			return "", domain.ErrInvalidShortened
+func (uc *Usecase) CreateShortenedBatch(ctx context.Context, urls []string) (_ []domain.BatchResult, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CreateShortenedBatch",
+		trace.WithAttributes(attribute.Int("batch.size", len(urls))))
+	defer func() { tracing.End(span, err) }()
+
+	if len(urls) == 0 || (uc.maxBatch > 0 && len(urls) > uc.maxBatch) {
+		return nil, domain.ErrInvalidBatch
+	}
+
+	results := make([]domain.BatchResult, len(urls))
+	positions := make(map[string][]int, len(urls))
+	pending := make([]string, 0, len(urls))
+
+	for i, url := range urls {
+		results[i].URL = url
+
+		if uc.protec {
+			ok := false
+			url, ok = uc.validator.ValidateURL(url)
+			if !ok {
+				results[i].Err = domain.ErrInvalidURL
+				continue
+			}
+		}
+
+		if err := uc.checkPolicy(url); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		if _, ok := positions[url]; !ok {
+			pending = append(pending, url)
+		}
+
+		positions[url] = append(positions[url], i)
+	}
+
+	resolve := func(url string, link domain.Link, err error) {
+		for _, i := range positions[url] {
+			results[i].Link = link
+			results[i].Err = err
+		}
+	}
+
+	for attempt := range uc.maxAttempts {
+		if len(pending) == 0 {
+			break
+		}
+
+		retry := make([]string, 0)
+		links := make([]domain.Link, 0, len(pending))
+		for _, url := range pending {
+			shortened, err := uc.gen.Generate(ctx, url, attempt)
+			if err != nil {
+				for _, url := range pending {
+					resolve(url, domain.Link{}, err)
+				}
+
+				return results, nil
+			}
+
+			if uc.validator.IsReserved(shortened) {
+				uc.metrics.CollisionRetry()
+				retry = append(retry, url)
+				continue
+			}
+
+			links = append(links, domain.Link{Original: url, Shortened: shortened})
+		}
+
+		if len(links) == 0 {
+			pending = retry
+			continue
+		}
+
+		errs, err := uc.repo.SaveBatch(ctx, links)
+		if err != nil {
+			for _, url := range pending {
+				resolve(url, domain.Link{}, err)
+			}
+
+			return results, nil
+		}
+
+		for i, link := range links {
+			if errs[i] == nil {
+				uc.metrics.LinkCreated()
+				resolve(link.Original, link, nil)
+				continue
+			}
+
+			if !errors.Is(errs[i], domain.ErrAlreadyExist) {
+				resolve(link.Original, domain.Link{}, errs[i])
+				continue
+			}
+
+			existing, err := uc.repo.GetByOriginal(ctx, link.Original)
+			if err == nil {
+				uc.metrics.DedupHit()
+				resolve(link.Original, existing, nil)
+				continue
+			}
+
+			if !errors.Is(err, domain.ErrNotFound) {
+				resolve(link.Original, domain.Link{}, err)
+				continue
+			}
+
+			uc.metrics.CollisionRetry()
+			retry = append(retry, link.Original)
+		}
+
+		pending = retry
+	}
+
+	for _, url := range pending {
+		uc.metrics.MaxAttemptsExceeded()
+		resolve(url, domain.Link{}, errMaxAttemptsExceeded)
+	}
+
+	return results, nil
+}
+
+func (uc *Usecase) createWithAlias(ctx context.Context, link domain.Link) (domain.Link, error) {
+	if !uc.validator.ValidateAlias(link.Shortened) {
+		return domain.Link{}, domain.ErrInvalidAlias
+	}
+
+	if !link.Reusable() {
+		if err := uc.repo.Save(ctx, link); err != nil {
+			return domain.Link{}, err
+		}
+
+		uc.metrics.LinkCreated()
+		return link, nil
+	}
+
+	existing, err := uc.repo.GetByOriginal(ctx, link.Original)
+	if err == nil {
+		if existing.Shortened == link.Shortened {
+			uc.metrics.DedupHit()
+			return existing, nil
+		}
+
+		return domain.Link{}, domain.ErrAlreadyExist
+	}
+
+	if !errors.Is(err, domain.ErrNotFound) {
+		return domain.Link{}, err
+	}
+
+	if err = uc.repo.Save(ctx, link); err != nil {
+		return domain.Link{}, err
+	}
+
+	uc.metrics.LinkCreated()
+	return link, nil
+}
+
+func (uc *Usecase) checkPolicy(url string) error {
+	if uc.policy == nil {
+		return nil
+	}
+
+	return uc.policy.Check(url)
+}
+
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return "", domain.ErrInvalidPassword
+		}
+
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func (uc *Usecase) checkPassword(link domain.Link, password string) error {
+	if password == "" {
+		return domain.ErrPasswordRequired
+	}
+
+	if uc.lockout != nil && uc.lockout.Locked(link.Shortened) {
+		return domain.ErrTooManyAttempts
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+		if uc.lockout != nil {
+			uc.lockout.Fail(link.Shortened)
 		}
+
+		return domain.ErrInvalidPassword
+	}
+
+	if uc.lockout != nil {
+		uc.lockout.Reset(link.Shortened)
 	}
 
-	origin, err := uc.repo.GetByShortened(ctx, shortened)
+	return nil
+}
+
+func expiration(params domain.CreateParams) (*time.Time, error) {
+	if params.TTL < 0 || params.TTL > domain.MaxTTL || (params.TTL > 0 && params.ExpiresAt != nil) {
+		return nil, domain.ErrInvalidExpiration
+	}
+
+	if params.TTL > 0 {
+		expiresAt := time.Now().Add(params.TTL).UTC()
+		return &expiresAt, nil
+	}
+
+	if params.ExpiresAt != nil {
+		if !params.ExpiresAt.After(time.Now()) {
+			return nil, domain.ErrInvalidExpiration
+		}
+
+		expiresAt := params.ExpiresAt.UTC()
+		return &expiresAt, nil
+	}
+
+	return nil, nil
+}
+
+func (uc *Usecase) GetOriginalByShortened(ctx context.Context, params domain.ResolveParams) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetOriginalByShortened",
+		trace.WithAttributes(attribute.String("link.shortened", params.Shortened)))
+	defer func() { tracing.End(span, err) }()
+
+	link, err := uc.getLink(ctx, params.Shortened)
 	if err != nil {
-		if err == domain.ErrNotFound {
+		return "", err
+	}
+
+	if link.Expired(time.Now()) {
+		return "", domain.ErrExpired
+	}
+
+	if link.Protected() {
+		if err := uc.checkPassword(link, params.Password); err != nil {
 			return "", err
 		}
+	}
+
+	if uc.clicks != nil {
+		uc.clicks.Record(domain.Click{
+			Shortened: link.Shortened,
+			At:        time.Now().UTC(),
+			Referrer:  params.Referrer,
+			UserAgent: params.UserAgent,
+			IP:        params.IP,
+		})
+	}
+
+	return link.Original, nil
+}
+
+func (uc *Usecase) GetLink(ctx context.Context, shortened string) (_ domain.Link, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetLink",
+		trace.WithAttributes(attribute.String("link.shortened", shortened)))
+	defer func() { tracing.End(span, err) }()
+
+	link, err := uc.getLink(ctx, shortened)
+	if err != nil {
+		return domain.Link{}, err
+	}
+
+	if link.Expired(time.Now()) {
+		return domain.Link{}, domain.ErrExpired
+	}
+
+	return link, nil
+}
+
+func (uc *Usecase) GetStats(ctx context.Context, shortened string) (_ domain.ClickStats, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.GetStats",
+		trace.WithAttributes(attribute.String("link.shortened", shortened)))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := uc.getLink(ctx, shortened); err != nil {
+		return domain.ClickStats{}, err
+	}
+
+	return uc.repo.GetClickStats(ctx, shortened)
+}
+
+func (uc *Usecase) DeleteShortened(ctx context.Context, shortened string) (err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.DeleteShortened",
+		trace.WithAttributes(attribute.String("link.shortened", shortened)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := uc.validateShortened(shortened); err != nil {
+		return err
+	}
+
+	return uc.repo.Delete(ctx, shortened)
+}
 
+func (uc *Usecase) UpdateOriginal(ctx context.Context, shortened, url string) (_ string, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.UpdateOriginal",
+		trace.WithAttributes(attribute.String("link.shortened", shortened)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := uc.validateShortened(shortened); err != nil {
 		return "", err
 	}
 
-	return origin, nil
+	if uc.protec {
+		ok := false
+		url, ok = uc.validator.ValidateURL(url)
+		if !ok {
+			return "", domain.ErrInvalidURL
+		}
+	}
+
+	if err := uc.checkPolicy(url); err != nil {
+		return "", err
+	}
+
+	if err := uc.repo.UpdateOriginal(ctx, shortened, url); err != nil {
+		return "", err
+	}
+
+	return url, nil
+}
+
+func (uc *Usecase) ListLinks(ctx context.Context, filter domain.ListFilter) (_ domain.LinkPage, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ListLinks")
+	defer func() { tracing.End(span, err) }()
+
+	if filter.Limit == 0 {
+		filter.Limit = defaultListLimit
+	}
+
+	if filter.Limit < 0 || filter.Limit > maxListLimit {
+		return domain.LinkPage{}, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidFilter, maxListLimit)
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedBefore != nil && !filter.CreatedFrom.Before(*filter.CreatedBefore) {
+		return domain.LinkPage{}, fmt.Errorf("%w: created_from must be before created_before", domain.ErrInvalidFilter)
+	}
+
+	if filter.Domain != "" {
+		host, err := idna.Lookup.ToASCII(strings.TrimSuffix(strings.ToLower(filter.Domain), "."))
+		if err != nil || host == "" {
+			return domain.LinkPage{}, fmt.Errorf("%w: invalid domain", domain.ErrInvalidFilter)
+		}
+
+		filter.Domain = host
+	}
+
+	filter.CreatedFrom = utc(filter.CreatedFrom)
+	filter.CreatedBefore = utc(filter.CreatedBefore)
+
+	return uc.repo.List(ctx, filter)
+}
+
+func utc(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	u := t.UTC()
+	return &u
+}
+
+func (uc *Usecase) ExportLinks(ctx context.Context, w io.Writer, options transfer.ExportOptions) (_ int, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ExportLinks",
+		trace.WithAttributes(
+			attribute.String("transfer.format", options.Format),
+			attribute.Bool("transfer.password_hashes", options.PasswordHashes)))
+	defer func() { tracing.End(span, err) }()
+
+	return transfer.Export(ctx, uc.repo, w, options)
+}
+
+func (uc *Usecase) ImportLinks(ctx context.Context, r io.Reader, options transfer.ImportOptions) (_ transfer.ImportResult, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.ImportLinks",
+		trace.WithAttributes(
+			attribute.String("transfer.format", options.Format),
+			attribute.String("transfer.conflict", options.Conflict)))
+	defer func() { tracing.End(span, err) }()
+
+	return transfer.Import(ctx, uc.repo, uc.validator, uc.policy, r, options)
+}
+
+// CanonicalizeLinks rewrites URLs stored before canonicalization was
+// introduced or changed. With dryRun it only reports what would change.
+func (uc *Usecase) CanonicalizeLinks(ctx context.Context, dryRun bool) (_ domain.CanonicalizeResult, err error) {
+	ctx, span := tracer.Start(ctx, "Usecase.CanonicalizeLinks",
+		trace.WithAttributes(attribute.Bool("canonicalize.dry_run", dryRun)))
+	defer func() { tracing.End(span, err) }()
+
+	result := domain.CanonicalizeResult{}
+
+	err = uc.repo.ForEachLink(ctx, func(link domain.Link) error {
+		result.Checked++
+
+		canonical, ok := uc.validator.ValidateURL(link.Original)
+		if !ok {
+			result.Invalid = append(result.Invalid, link.Shortened)
+			return nil
+		}
+
+		if canonical == link.Original {
+			return nil
+		}
+
+		if dryRun {
+			result.Updated++
+			return nil
+		}
+
+		if err := uc.repo.UpdateOriginal(ctx, link.Shortened, canonical); err != nil {
+			if errors.Is(err, domain.ErrAlreadyExist) {
+				result.Conflicts = append(result.Conflicts, link.Shortened)
+				return nil
+			}
+
+			return err
+		}
+
+		result.Updated++
+		return nil
+	})
+
+	return result, err
+}
+
+func (uc *Usecase) getLink(ctx context.Context, shortened string) (domain.Link, error) {
+	if err := uc.validateShortened(shortened); err != nil {
+		return domain.Link{}, err
+	}
+
+	return uc.repo.GetByShortened(ctx, shortened)
+}
+
+func (uc *Usecase) validateShortened(shortened string) error {
+	if uc.protec {
+		if !uc.validator.ValidateShortened(shortened) && !uc.validator.ValidateAlias(shortened) {
+			return domain.ErrInvalidShortened
+		}
+	}
+
+	return nil
 }
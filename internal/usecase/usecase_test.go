@@ -3,7 +3,9 @@ package usecase_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"shortener/internal/domain"
 	"shortener/internal/usecase"
@@ -11,15 +13,29 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// allowGenerated lets every generated code pass the reserved check. Call it
+// after the case's own expectations, gomock matches those first.
+func allowGenerated(validator *mocks.MockValidator) *mocks.MockValidator {
+	validator.EXPECT().IsReserved(gomock.Any()).Return(false).AnyTimes()
+	return validator
+}
+
 func TestCreateShortened(t *testing.T) {
 	ctx := context.Background()
 	maxAttempts := 2
+	future := time.Now().Add(time.Hour).UTC()
+	past := time.Now().Add(-time.Hour).UTC()
 
 	tests := []struct {
 		name          string
 		original      string
+		alias         string
+		expiresAt     *time.Time
+		ttl           time.Duration
 		wantShortened string
 		setUpMocks    func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator)
 		wantErr       assert.ErrorAssertionFunc
@@ -30,9 +46,9 @@ func TestCreateShortened(t *testing.T) {
 			original:      "example",
 			wantShortened: "ok",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("", domain.ErrNotFound)
-				gen.EXPECT().Generate().Return("ok", nil)
-				repo.EXPECT().Save(ctx, "example", "ok").Return(nil)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok"}).Return(nil)
 			},
 			wantErr:    assert.NoError,
 			protection: false,
@@ -52,7 +68,7 @@ func TestCreateShortened(t *testing.T) {
 			original:      "example",
 			wantShortened: "exist",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("exist", nil)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{Original: "example", Shortened: "exist"}, nil)
 			},
 			wantErr:    assert.NoError,
 			protection: false,
@@ -62,7 +78,7 @@ func TestCreateShortened(t *testing.T) {
 			original:      "example",
 			wantShortened: "",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("", errors.New("db error"))
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, errors.New("db error"))
 			},
 			wantErr:    assert.Error,
 			protection: false,
@@ -72,9 +88,9 @@ func TestCreateShortened(t *testing.T) {
 			original:      "example",
 			wantShortened: "",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("", domain.ErrNotFound)
-				gen.EXPECT().Generate().Return("ok", nil)
-				repo.EXPECT().Save(ctx, "example", "ok").Return(errors.New("db error"))
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok"}).Return(errors.New("db error"))
 			},
 			wantErr:    assert.Error,
 			protection: false,
@@ -84,8 +100,8 @@ func TestCreateShortened(t *testing.T) {
 			original:      "example",
 			wantShortened: "",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("", domain.ErrNotFound)
-				gen.EXPECT().Generate().Return("", errors.New("gen error"))
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("", errors.New("gen error"))
 			},
 			wantErr:    assert.Error,
 			protection: false,
@@ -95,25 +111,167 @@ func TestCreateShortened(t *testing.T) {
 			original:      "example",
 			wantShortened: "ok",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("", domain.ErrNotFound)
-				gen.EXPECT().Generate().Return("collision", nil)
-				repo.EXPECT().Save(ctx, "example", "collision").Return(domain.ErrAlreadyExist)
-				repo.EXPECT().GetByOriginal(ctx, "example").Return("", domain.ErrNotFound)
-				gen.EXPECT().Generate().Return("ok", nil)
-				repo.EXPECT().Save(ctx, "example", "ok").Return(nil)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("collision", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "collision"}).Return(domain.ErrAlreadyExist)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "example", 1).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok"}).Return(nil)
+			},
+			wantErr:    assert.NoError,
+			protection: false,
+		},
+		{
+			name:          "alias",
+			original:      "example",
+			alias:         "spring-sale",
+			wantShortened: "spring-sale",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateAlias("spring-sale").Return(true)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "spring-sale"}).Return(nil)
+			},
+			wantErr:    assert.NoError,
+			protection: false,
+		},
+		{
+			name:          "invalid alias",
+			original:      "example",
+			alias:         "api",
+			wantShortened: "",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateAlias("api").Return(false)
+			},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidAlias)
+			},
+			protection: false,
+		},
+		{
+			name:          "alias taken",
+			original:      "example",
+			alias:         "spring-sale",
+			wantShortened: "",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateAlias("spring-sale").Return(true)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "spring-sale"}).Return(domain.ErrAlreadyExist)
+			},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrAlreadyExist)
+			},
+			protection: false,
+		},
+		{
+			name:          "alias same original",
+			original:      "example",
+			alias:         "spring-sale",
+			wantShortened: "spring-sale",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateAlias("spring-sale").Return(true)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{Original: "example", Shortened: "spring-sale"}, nil)
+			},
+			wantErr:    assert.NoError,
+			protection: false,
+		},
+		{
+			name:          "alias other original",
+			original:      "example",
+			alias:         "spring-sale",
+			wantShortened: "",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateAlias("spring-sale").Return(true)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{Original: "example", Shortened: "other"}, nil)
+			},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrAlreadyExist)
+			},
+			protection: false,
+		},
+		{
+			name:          "reserved generated code",
+			original:      "example",
+			wantShortened: "ok",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound).Times(2)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("api", nil)
+				validator.EXPECT().IsReserved("api").Return(true)
+				gen.EXPECT().Generate(gomock.Any(), "example", 1).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok"}).Return(nil)
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:          "expires at",
+			original:      "example",
+			expiresAt:     &future,
+			wantShortened: "ok",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok", ExpiresAt: &future}).Return(nil)
+			},
+			wantErr:    assert.NoError,
+			protection: false,
+		},
+		{
+			name:          "ttl",
+			original:      "example",
+			ttl:           time.Minute,
+			wantShortened: "ok",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, link domain.Link) error {
+					assert.NotNil(t, link.ExpiresAt)
+					assert.WithinDuration(t, time.Now().Add(time.Minute), *link.ExpiresAt, time.Second)
+					return nil
+				})
 			},
 			wantErr:    assert.NoError,
 			protection: false,
 		},
+		{
+			name:          "expires at in the past",
+			original:      "example",
+			expiresAt:     &past,
+			wantShortened: "",
+			setUpMocks:    func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidExpiration)
+			},
+			protection: false,
+		},
+		{
+			name:          "both ttl and expires at",
+			original:      "example",
+			expiresAt:     &future,
+			ttl:           time.Minute,
+			wantShortened: "",
+			setUpMocks:    func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidExpiration)
+			},
+			protection: false,
+		},
+		{
+			name:          "ttl over max",
+			original:      "example",
+			ttl:           domain.MaxTTL + time.Second,
+			wantShortened: "",
+			setUpMocks:    func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidExpiration)
+			},
+			protection: false,
+		},
 		{
 			name:          "max attempts exceeded",
 			original:      "example",
 			wantShortened: "",
 			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
 				for i := 0; i < maxAttempts; i++ {
-					repo.EXPECT().GetByOriginal(ctx, "example").Return("", domain.ErrNotFound)
-					gen.EXPECT().Generate().Return("collision", nil)
-					repo.EXPECT().Save(ctx, "example", "collision").Return(domain.ErrAlreadyExist)
+					repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+					gen.EXPECT().Generate(gomock.Any(), "example", i).Return("collision", nil)
+					repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "collision"}).Return(domain.ErrAlreadyExist)
 				}
 			},
 			wantErr:    assert.Error,
@@ -131,6 +289,7 @@ func TestCreateShortened(t *testing.T) {
 			validator := mocks.NewMockValidator(ctrl)
 
 			tt.setUpMocks(repo, gen, validator)
+			allowGenerated(validator)
 
 			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
 				Repository:  repo,
@@ -140,9 +299,14 @@ func TestCreateShortened(t *testing.T) {
 				Protection:  tt.protection,
 			})
 
-			gotShortened, err := uc.CreateShortened(ctx, tt.original)
+			gotLink, err := uc.CreateShortened(ctx, domain.CreateParams{
+				URL:       tt.original,
+				Alias:     tt.alias,
+				ExpiresAt: tt.expiresAt,
+				TTL:       tt.ttl,
+			})
 			tt.wantErr(t, err)
-			assert.Equal(t, tt.wantShortened, gotShortened)
+			assert.Equal(t, tt.wantShortened, gotLink.Shortened)
 		})
 	}
 }
@@ -150,14 +314,17 @@ func TestCreateShortened(t *testing.T) {
 func TestGetShortenedByOriginal(t *testing.T) {
 	ctx := context.Background()
 	maxAttempts := 1
+	past := time.Now().Add(-time.Hour)
 
 	tests := []struct {
 		name       string
 		shortened  string
 		mockRes    string
+		expiresAt  *time.Time
 		wantValue  string
 		mockErr    error
 		wantErr    assert.ErrorAssertionFunc
+		wantClick  bool
 		protection bool
 	}{
 		{
@@ -167,6 +334,7 @@ func TestGetShortenedByOriginal(t *testing.T) {
 			wantValue:  "example",
 			mockErr:    nil,
 			wantErr:    assert.NoError,
+			wantClick:  true,
 			protection: false,
 		},
 		{
@@ -186,6 +354,16 @@ func TestGetShortenedByOriginal(t *testing.T) {
 			mockErr:   domain.ErrNotFound,
 			wantErr:   assert.Error,
 		},
+		{
+			name:       "expired",
+			shortened:  "ok",
+			mockRes:    "example",
+			expiresAt:  &past,
+			wantValue:  "",
+			mockErr:    nil,
+			wantErr:    assert.Error,
+			protection: false,
+		},
 		{
 			name:       "repo error",
 			shortened:  "ok",
@@ -205,25 +383,776 @@ func TestGetShortenedByOriginal(t *testing.T) {
 			repo := mocks.NewMockRepository(ctrl)
 			gen := mocks.NewMockGenerator(ctrl)
 			validator := mocks.NewMockValidator(ctrl)
+			clicks := mocks.NewMockClickRecorder(ctrl)
 
 			if tt.protection {
 				validator.EXPECT().ValidateShortened(tt.shortened).Return(false)
+				validator.EXPECT().ValidateAlias(tt.shortened).Return(false)
 			} else {
-				repo.EXPECT().GetByShortened(ctx, tt.shortened).Return(tt.mockRes, tt.mockErr)
+				repo.EXPECT().GetByShortened(gomock.Any(), tt.shortened).Return(domain.Link{Original: tt.mockRes, Shortened: tt.shortened, ExpiresAt: tt.expiresAt}, tt.mockErr)
+			}
+
+			if tt.wantClick {
+				clicks.EXPECT().Record(gomock.Any()).Do(func(click domain.Click) {
+					assert.Equal(t, tt.shortened, click.Shortened)
+					assert.Equal(t, "https://referrer.example", click.Referrer)
+					assert.Equal(t, "agent", click.UserAgent)
+					assert.Equal(t, "10.0.0.1", click.IP)
+					assert.False(t, click.At.IsZero())
+				})
 			}
 
 			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
 				Repository:  repo,
 				Generator:   gen,
 				Validator:   validator,
+				Clicks:      clicks,
 				MaxAttempts: maxAttempts,
 				Protection:  tt.protection,
 			})
 
-			gotOriginal, err := uc.GetOriginalByShortened(ctx, tt.shortened)
+			gotOriginal, err := uc.GetOriginalByShortened(ctx, domain.ResolveParams{
+				Shortened: tt.shortened,
+				Referrer:  "https://referrer.example",
+				UserAgent: "agent",
+				IP:        "10.0.0.1",
+			})
 
 			tt.wantErr(t, err)
 			assert.Equal(t, tt.wantValue, gotOriginal)
 		})
 	}
 }
+
+func TestGetStats(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	stats := domain.ClickStats{
+		Total: 3,
+		Daily: []domain.DailyClicks{{Day: day, Count: 3}},
+	}
+
+	tests := []struct {
+		name       string
+		setUpMocks func(repo *mocks.MockRepository)
+		wantStats  domain.ClickStats
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name: "ok",
+			setUpMocks: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetByShortened(gomock.Any(), "ok").Return(domain.Link{Original: "example", Shortened: "ok"}, nil)
+				repo.EXPECT().GetClickStats(gomock.Any(), "ok").Return(stats, nil)
+			},
+			wantStats: stats,
+			wantErr:   assert.NoError,
+		},
+		{
+			name: "not found",
+			setUpMocks: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetByShortened(gomock.Any(), "ok").Return(domain.Link{}, domain.ErrNotFound)
+			},
+			wantStats: domain.ClickStats{},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrNotFound)
+			},
+		},
+		{
+			name: "stats error",
+			setUpMocks: func(repo *mocks.MockRepository) {
+				repo.EXPECT().GetByShortened(gomock.Any(), "ok").Return(domain.Link{Original: "example", Shortened: "ok"}, nil)
+				repo.EXPECT().GetClickStats(gomock.Any(), "ok").Return(domain.ClickStats{}, errors.New("db error"))
+			},
+			wantStats: domain.ClickStats{},
+			wantErr:   assert.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			tt.setUpMocks(repo)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   mocks.NewMockGenerator(ctrl),
+				Validator:   mocks.NewMockValidator(ctrl),
+				MaxAttempts: 1,
+			})
+
+			gotStats, err := uc.GetStats(ctx, "ok")
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantStats, gotStats)
+		})
+	}
+}
+
+func TestDeleteShortened(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		shortened  string
+		setUpMocks func(repo *mocks.MockRepository, validator *mocks.MockValidator)
+		wantErr    assert.ErrorAssertionFunc
+		protection bool
+	}{
+		{
+			name:      "ok",
+			shortened: "ok",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				repo.EXPECT().Delete(gomock.Any(), "ok").Return(nil)
+			},
+			wantErr:    assert.NoError,
+			protection: false,
+		},
+		{
+			name:      "not found",
+			shortened: "ok",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				repo.EXPECT().Delete(gomock.Any(), "ok").Return(domain.ErrNotFound)
+			},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrNotFound)
+			},
+			protection: false,
+		},
+		{
+			name:      "protection",
+			shortened: "enemy",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateShortened("enemy").Return(false)
+				validator.EXPECT().ValidateAlias("enemy").Return(false)
+			},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidShortened)
+			},
+			protection: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			validator := mocks.NewMockValidator(ctrl)
+
+			tt.setUpMocks(repo, validator)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   mocks.NewMockGenerator(ctrl),
+				Validator:   validator,
+				MaxAttempts: 1,
+				Protection:  tt.protection,
+			})
+
+			tt.wantErr(t, uc.DeleteShortened(ctx, tt.shortened))
+		})
+	}
+}
+
+func TestUpdateOriginal(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		url          string
+		setUpMocks   func(repo *mocks.MockRepository, validator *mocks.MockValidator)
+		wantOriginal string
+		wantErr      assert.ErrorAssertionFunc
+		protection   bool
+	}{
+		{
+			name: "ok",
+			url:  "https://example.com/",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateShortened("ok").Return(true)
+				validator.EXPECT().ValidateURL("https://example.com/").Return("https://example.com", true)
+				repo.EXPECT().UpdateOriginal(gomock.Any(), "ok", "https://example.com").Return(nil)
+			},
+			wantOriginal: "https://example.com",
+			wantErr:      assert.NoError,
+			protection:   true,
+		},
+		{
+			name: "invalid url",
+			url:  "invalid",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateShortened("ok").Return(true)
+				validator.EXPECT().ValidateURL("invalid").Return("", false)
+			},
+			wantOriginal: "",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidURL)
+			},
+			protection: true,
+		},
+		{
+			name: "original taken",
+			url:  "example",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				repo.EXPECT().UpdateOriginal(gomock.Any(), "ok", "example").Return(domain.ErrAlreadyExist)
+			},
+			wantOriginal: "",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrAlreadyExist)
+			},
+			protection: false,
+		},
+		{
+			name: "not found",
+			url:  "example",
+			setUpMocks: func(repo *mocks.MockRepository, validator *mocks.MockValidator) {
+				repo.EXPECT().UpdateOriginal(gomock.Any(), "ok", "example").Return(domain.ErrNotFound)
+			},
+			wantOriginal: "",
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrNotFound)
+			},
+			protection: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			validator := mocks.NewMockValidator(ctrl)
+
+			tt.setUpMocks(repo, validator)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   mocks.NewMockGenerator(ctrl),
+				Validator:   validator,
+				MaxAttempts: 1,
+				Protection:  tt.protection,
+			})
+
+			gotOriginal, err := uc.UpdateOriginal(ctx, "ok", tt.url)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantOriginal, gotOriginal)
+		})
+	}
+}
+
+func TestCreateShortenedBatch(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		urls        []string
+		setUpMocks  func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator)
+		wantResults []domain.BatchResult
+		wantErr     assert.ErrorAssertionFunc
+	}{
+		{
+			name: "ok",
+			urls: []string{"https://a.com/", "invalid", "https://a.com", "https://b.com"},
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateURL("https://a.com/").Return("https://a.com", true)
+				validator.EXPECT().ValidateURL("invalid").Return("", false)
+				validator.EXPECT().ValidateURL("https://a.com").Return("https://a.com", true)
+				validator.EXPECT().ValidateURL("https://b.com").Return("https://b.com", true)
+				gen.EXPECT().Generate(gomock.Any(), "https://a.com", 0).Return("aaa", nil)
+				gen.EXPECT().Generate(gomock.Any(), "https://b.com", 0).Return("bbb", nil)
+				repo.EXPECT().SaveBatch(gomock.Any(), []domain.Link{
+					{Original: "https://a.com", Shortened: "aaa"},
+					{Original: "https://b.com", Shortened: "bbb"},
+				}).Return([]error{nil, nil}, nil)
+			},
+			wantResults: []domain.BatchResult{
+				{URL: "https://a.com/", Link: domain.Link{Original: "https://a.com", Shortened: "aaa"}},
+				{URL: "invalid", Err: domain.ErrInvalidURL},
+				{URL: "https://a.com", Link: domain.Link{Original: "https://a.com", Shortened: "aaa"}},
+				{URL: "https://b.com", Link: domain.Link{Original: "https://b.com", Shortened: "bbb"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "existing and collision",
+			urls: []string{"https://a.com", "https://b.com"},
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateURL("https://a.com").Return("https://a.com", true)
+				validator.EXPECT().ValidateURL("https://b.com").Return("https://b.com", true)
+				gen.EXPECT().Generate(gomock.Any(), "https://a.com", 0).Return("aaa", nil)
+				gen.EXPECT().Generate(gomock.Any(), "https://b.com", 0).Return("bbb", nil)
+				repo.EXPECT().SaveBatch(gomock.Any(), []domain.Link{
+					{Original: "https://a.com", Shortened: "aaa"},
+					{Original: "https://b.com", Shortened: "bbb"},
+				}).Return([]error{domain.ErrAlreadyExist, domain.ErrAlreadyExist}, nil)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "https://a.com").Return(domain.Link{Original: "https://a.com", Shortened: "old"}, nil)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "https://b.com").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "https://b.com", 1).Return("ccc", nil)
+				repo.EXPECT().SaveBatch(gomock.Any(), []domain.Link{
+					{Original: "https://b.com", Shortened: "ccc"},
+				}).Return([]error{nil}, nil)
+			},
+			wantResults: []domain.BatchResult{
+				{URL: "https://a.com", Link: domain.Link{Original: "https://a.com", Shortened: "old"}},
+				{URL: "https://b.com", Link: domain.Link{Original: "https://b.com", Shortened: "ccc"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "reserved generated code",
+			urls: []string{"https://a.com", "https://b.com"},
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateURL("https://a.com").Return("https://a.com", true)
+				validator.EXPECT().ValidateURL("https://b.com").Return("https://b.com", true)
+				gen.EXPECT().Generate(gomock.Any(), "https://a.com", 0).Return("api", nil)
+				validator.EXPECT().IsReserved("api").Return(true)
+				gen.EXPECT().Generate(gomock.Any(), "https://b.com", 0).Return("bbb", nil)
+				repo.EXPECT().SaveBatch(gomock.Any(), []domain.Link{
+					{Original: "https://b.com", Shortened: "bbb"},
+				}).Return([]error{nil}, nil)
+				gen.EXPECT().Generate(gomock.Any(), "https://a.com", 1).Return("aaa", nil)
+				repo.EXPECT().SaveBatch(gomock.Any(), []domain.Link{
+					{Original: "https://a.com", Shortened: "aaa"},
+				}).Return([]error{nil}, nil)
+			},
+			wantResults: []domain.BatchResult{
+				{URL: "https://a.com", Link: domain.Link{Original: "https://a.com", Shortened: "aaa"}},
+				{URL: "https://b.com", Link: domain.Link{Original: "https://b.com", Shortened: "bbb"}},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name: "db error",
+			urls: []string{"https://a.com"},
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {
+				validator.EXPECT().ValidateURL("https://a.com").Return("https://a.com", true)
+				gen.EXPECT().Generate(gomock.Any(), "https://a.com", 0).Return("aaa", nil)
+				repo.EXPECT().SaveBatch(gomock.Any(), gomock.Any()).Return(nil, errors.New("db error"))
+			},
+			wantResults: []domain.BatchResult{
+				{URL: "https://a.com", Err: errors.New("db error")},
+			},
+			wantErr: assert.NoError,
+		},
+		{
+			name:        "empty",
+			urls:        nil,
+			setUpMocks:  func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {},
+			wantResults: nil,
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidBatch)
+			},
+		},
+		{
+			name:        "too large",
+			urls:        []string{"1", "2", "3", "4", "5"},
+			setUpMocks:  func(repo *mocks.MockRepository, gen *mocks.MockGenerator, validator *mocks.MockValidator) {},
+			wantResults: nil,
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidBatch)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			gen := mocks.NewMockGenerator(ctrl)
+			validator := mocks.NewMockValidator(ctrl)
+
+			tt.setUpMocks(repo, gen, validator)
+			allowGenerated(validator)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:   repo,
+				Generator:    gen,
+				Validator:    validator,
+				MaxAttempts:  2,
+				MaxBatchSize: 4,
+				Protection:   true,
+			})
+
+			gotResults, err := uc.CreateShortenedBatch(ctx, tt.urls)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantResults, gotResults)
+		})
+	}
+}
+
+func TestCreateShortenedMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		setUpMocks func(repo *mocks.MockRepository, gen *mocks.MockGenerator, metrics *mocks.MockMetrics)
+	}{
+		{
+			name: "created after collision",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, metrics *mocks.MockMetrics) {
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound).Times(2)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("collision", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "collision"}).Return(domain.ErrAlreadyExist)
+				gen.EXPECT().Generate(gomock.Any(), "example", 1).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok"}).Return(nil)
+				metrics.EXPECT().CollisionRetry()
+				metrics.EXPECT().LinkCreated()
+			},
+		},
+		{
+			name: "dedup",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, metrics *mocks.MockMetrics) {
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{Original: "example", Shortened: "exist"}, nil)
+				metrics.EXPECT().DedupHit()
+			},
+		},
+		{
+			name: "max attempts exceeded",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, metrics *mocks.MockMetrics) {
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound).Times(2)
+				gen.EXPECT().Generate(gomock.Any(), "example", gomock.Any()).Return("collision", nil).Times(2)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "collision"}).Return(domain.ErrAlreadyExist).Times(2)
+				metrics.EXPECT().CollisionRetry().Times(2)
+				metrics.EXPECT().MaxAttemptsExceeded()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			gen := mocks.NewMockGenerator(ctrl)
+			metrics := mocks.NewMockMetrics(ctrl)
+
+			tt.setUpMocks(repo, gen, metrics)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   gen,
+				Validator:   allowGenerated(mocks.NewMockValidator(ctrl)),
+				Metrics:     metrics,
+				MaxAttempts: 2,
+			})
+
+			_, _ = uc.CreateShortened(ctx, domain.CreateParams{URL: "example"})
+		})
+	}
+}
+
+func TestCreateShortenedPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		setUpMocks func(repo *mocks.MockRepository, gen *mocks.MockGenerator, policy *mocks.MockPolicy)
+		wantErr    error
+	}{
+		{
+			name: "allowed",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, policy *mocks.MockPolicy) {
+				policy.EXPECT().Check("example").Return(nil)
+				repo.EXPECT().GetByOriginal(gomock.Any(), "example").Return(domain.Link{}, domain.ErrNotFound)
+				gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("ok", nil)
+				repo.EXPECT().Save(gomock.Any(), domain.Link{Original: "example", Shortened: "ok"}).Return(nil)
+			},
+		},
+		{
+			name: "forbidden",
+			setUpMocks: func(repo *mocks.MockRepository, gen *mocks.MockGenerator, policy *mocks.MockPolicy) {
+				policy.EXPECT().Check("example").Return(domain.ErrForbiddenDestination)
+			},
+			wantErr: domain.ErrForbiddenDestination,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			gen := mocks.NewMockGenerator(ctrl)
+			policy := mocks.NewMockPolicy(ctrl)
+
+			tt.setUpMocks(repo, gen, policy)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   gen,
+				Validator:   allowGenerated(mocks.NewMockValidator(ctrl)),
+				Policy:      policy,
+				MaxAttempts: 2,
+			})
+
+			_, err := uc.CreateShortened(ctx, domain.CreateParams{URL: "example"})
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestCreateShortenedWithPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockRepository(ctrl)
+	gen := mocks.NewMockGenerator(ctrl)
+
+	gen.EXPECT().Generate(gomock.Any(), "example", 0).Return("ok", nil)
+	repo.EXPECT().Save(gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, link domain.Link) error {
+		assert.Equal(t, "ok", link.Shortened)
+		assert.True(t, link.Protected())
+		assert.NotEqual(t, "secret", link.PasswordHash)
+		return nil
+	})
+
+	uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+		Repository:  repo,
+		Generator:   gen,
+		Validator:   allowGenerated(mocks.NewMockValidator(ctrl)),
+		MaxAttempts: 1,
+	})
+
+	link, err := uc.CreateShortened(context.Background(), domain.CreateParams{URL: "example", Password: "secret"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", link.Shortened)
+
+	_, err = uc.CreateShortened(context.Background(), domain.CreateParams{URL: "example", Password: strings.Repeat("x", 73)})
+	assert.ErrorIs(t, err, domain.ErrInvalidPassword)
+}
+
+func TestGetOriginalWithPassword(t *testing.T) {
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	link := domain.Link{Original: "example", Shortened: "ok", PasswordHash: string(hash)}
+
+	tests := []struct {
+		name       string
+		password   string
+		setUpMocks func(lockout *mocks.MockLockout)
+		wantValue  string
+		wantErr    error
+	}{
+		{
+			name:       "password required",
+			setUpMocks: func(lockout *mocks.MockLockout) {},
+			wantErr:    domain.ErrPasswordRequired,
+		},
+		{
+			name:     "ok",
+			password: "secret",
+			setUpMocks: func(lockout *mocks.MockLockout) {
+				lockout.EXPECT().Locked("ok").Return(false)
+				lockout.EXPECT().Reset("ok")
+			},
+			wantValue: "example",
+		},
+		{
+			name:     "wrong password",
+			password: "guess",
+			setUpMocks: func(lockout *mocks.MockLockout) {
+				lockout.EXPECT().Locked("ok").Return(false)
+				lockout.EXPECT().Fail("ok")
+			},
+			wantErr: domain.ErrInvalidPassword,
+		},
+		{
+			name:     "locked",
+			password: "secret",
+			setUpMocks: func(lockout *mocks.MockLockout) {
+				lockout.EXPECT().Locked("ok").Return(true)
+			},
+			wantErr: domain.ErrTooManyAttempts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			lockout := mocks.NewMockLockout(ctrl)
+
+			repo.EXPECT().GetByShortened(gomock.Any(), "ok").Return(link, nil)
+			tt.setUpMocks(lockout)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   mocks.NewMockGenerator(ctrl),
+				Validator:   mocks.NewMockValidator(ctrl),
+				Lockout:     lockout,
+				MaxAttempts: 1,
+			})
+
+			got, err := uc.GetOriginalByShortened(ctx, domain.ResolveParams{Shortened: "ok", Password: tt.password})
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Equal(t, tt.wantValue, got)
+		})
+	}
+}
+
+func TestListLinks(t *testing.T) {
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	before := from.Add(24 * time.Hour)
+	page := domain.LinkPage{Links: []domain.Link{{Original: "https://xn--e1afmkfd.xn--p1ai/a", Shortened: "ok"}}}
+
+	tests := []struct {
+		name       string
+		filter     domain.ListFilter
+		setUpMocks func(repo *mocks.MockRepository)
+		wantPage   domain.LinkPage
+		wantErr    assert.ErrorAssertionFunc
+	}{
+		{
+			name:   "defaults",
+			filter: domain.ListFilter{Domain: "Пример.РФ.", CreatedFrom: &from},
+			setUpMocks: func(repo *mocks.MockRepository) {
+				repo.EXPECT().List(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, filter domain.ListFilter) (domain.LinkPage, error) {
+						assert.Equal(t, 50, filter.Limit)
+						assert.Equal(t, "xn--e1afmkfd.xn--p1ai", filter.Domain)
+						assert.Equal(t, time.UTC, filter.CreatedFrom.Location())
+						assert.True(t, from.Equal(*filter.CreatedFrom))
+						return page, nil
+					})
+			},
+			wantPage: page,
+			wantErr:  assert.NoError,
+		},
+		{
+			name:       "limit too large",
+			filter:     domain.ListFilter{Limit: 501},
+			setUpMocks: func(repo *mocks.MockRepository) {},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidFilter)
+			},
+		},
+		{
+			name:       "empty date range",
+			filter:     domain.ListFilter{CreatedFrom: &before, CreatedBefore: &from},
+			setUpMocks: func(repo *mocks.MockRepository) {},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidFilter)
+			},
+		},
+		{
+			name:   "invalid cursor",
+			filter: domain.ListFilter{Cursor: "bad"},
+			setUpMocks: func(repo *mocks.MockRepository) {
+				repo.EXPECT().List(gomock.Any(), gomock.Any()).Return(domain.LinkPage{}, domain.ErrInvalidCursor)
+			},
+			wantErr: func(t assert.TestingT, err error, _ ...interface{}) bool {
+				return assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			tt.setUpMocks(repo)
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   mocks.NewMockGenerator(ctrl),
+				Validator:   mocks.NewMockValidator(ctrl),
+				MaxAttempts: 1,
+			})
+
+			gotPage, err := uc.ListLinks(ctx, tt.filter)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantPage, gotPage)
+		})
+	}
+}
+
+func TestCanonicalizeLinks(t *testing.T) {
+	ctx := context.Background()
+	links := []domain.Link{
+		{Original: "http://a.com", Shortened: "same"},
+		{Original: "HTTP://A.com/x", Shortened: "lower"},
+		{Original: "HTTP://B.com", Shortened: "taken"},
+		{Original: "ftp://c.com", Shortened: "bad"},
+	}
+
+	tests := []struct {
+		name       string
+		dryRun     bool
+		setUpMocks func(repo *mocks.MockRepository)
+		wantResult domain.CanonicalizeResult
+	}{
+		{
+			name: "rewrite",
+			setUpMocks: func(repo *mocks.MockRepository) {
+				repo.EXPECT().UpdateOriginal(gomock.Any(), "lower", "http://a.com/x").Return(nil)
+				repo.EXPECT().UpdateOriginal(gomock.Any(), "taken", "http://b.com").Return(domain.ErrAlreadyExist)
+			},
+			wantResult: domain.CanonicalizeResult{Checked: 4, Updated: 1, Conflicts: []string{"taken"}, Invalid: []string{"bad"}},
+		},
+		{
+			name:       "dry run",
+			dryRun:     true,
+			setUpMocks: func(repo *mocks.MockRepository) {},
+			wantResult: domain.CanonicalizeResult{Checked: 4, Updated: 2, Invalid: []string{"bad"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockRepository(ctrl)
+			repo.EXPECT().ForEachLink(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, fn func(link domain.Link) error) error {
+					for _, link := range links {
+						if err := fn(link); err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+			tt.setUpMocks(repo)
+
+			validator := mocks.NewMockValidator(ctrl)
+			validator.EXPECT().ValidateURL(gomock.Any()).DoAndReturn(func(url string) (string, bool) {
+				scheme, rest, _ := strings.Cut(url, "://")
+				if strings.ToLower(scheme) != "http" {
+					return "", false
+				}
+				host, path, _ := strings.Cut(rest, "/")
+				if path != "" {
+					path = "/" + path
+				}
+				return "http://" + strings.ToLower(host) + path, true
+			}).AnyTimes()
+
+			uc, _ := usecase.NewUsecase(usecase.UsecaseOptions{
+				Repository:  repo,
+				Generator:   mocks.NewMockGenerator(ctrl),
+				Validator:   validator,
+				MaxAttempts: 1,
+			})
+
+			result, err := uc.CanonicalizeLinks(ctx, tt.dryRun)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantResult, result)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package validator
+
+import (
+	"net"
+	netURL "net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+type CanonicalizerOptions struct {
+	StripTracking  bool
+	TrackingParams []string
+	DropFragment   bool
+}
+
+type Canonicalizer struct {
+	stripTracking  bool
+	trackingParams map[string]struct{}
+	dropFragment   bool
+}
+
+func NewCanonicalizer(options CanonicalizerOptions) *Canonicalizer {
+	params := map[string]struct{}{
+		"fbclid": {},
+	}
+
+	for _, param := range options.TrackingParams {
+		params[strings.ToLower(param)] = struct{}{}
+	}
+
+	return &Canonicalizer{
+		stripTracking:  options.StripTracking,
+		trackingParams: params,
+		dropFragment:   options.DropFragment,
+	}
+}
+
+// Canonicalize brings an absolute http(s) URL to a single form so that
+// equivalent spellings of the same address compare equal.
+func (c *Canonicalizer) Canonicalize(parsed *netURL.URL) (string, bool) {
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", false
+	}
+
+	host, ok := canonicalHost(parsed.Scheme, parsed.Hostname(), parsed.Port())
+	if !ok {
+		return "", false
+	}
+	parsed.Host = host
+
+	path := strings.TrimRight(removeDotSegments(parsed.EscapedPath()), "/")
+	unescaped, err := netURL.PathUnescape(path)
+	if err != nil {
+		return "", false
+	}
+	parsed.Path, parsed.RawPath = unescaped, path
+
+	parsed.RawQuery = c.canonicalQuery(parsed.RawQuery)
+	parsed.ForceQuery = false
+
+	if c.dropFragment {
+		parsed.Fragment, parsed.RawFragment = "", ""
+	}
+
+	return parsed.String(), true
+}
+
+func canonicalHost(scheme, hostname, port string) (string, bool) {
+	if hostname == "" {
+		return "", false
+	}
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		hostname = ip.String()
+	} else {
+		ascii, err := idna.Lookup.ToASCII(strings.TrimSuffix(hostname, "."))
+		if err != nil || ascii == "" {
+			return "", false
+		}
+		hostname = ascii
+	}
+
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+
+	if port != "" {
+		return net.JoinHostPort(hostname, port), true
+	}
+
+	if strings.Contains(hostname, ":") {
+		return "[" + hostname + "]", true
+	}
+
+	return hostname, true
+}
+
+type queryParam struct {
+	key      string
+	value    string
+	hasValue bool
+}
+
+// canonicalQuery sorts parameters by name and re-escapes them. Unlike
+// url.Values.Encode it keeps a key without "=" as is, since "?flag" and
+// "?flag=" may mean different things to the destination.
+func (c *Canonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" || strings.Contains(rawQuery, ";") {
+		return rawQuery
+	}
+
+	params := make([]queryParam, 0, strings.Count(rawQuery, "&")+1)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+
+		rawKey, rawValue, hasValue := strings.Cut(pair, "=")
+
+		key, err := netURL.QueryUnescape(rawKey)
+		if err != nil {
+			return rawQuery
+		}
+
+		value, err := netURL.QueryUnescape(rawValue)
+		if err != nil {
+			return rawQuery
+		}
+
+		if c.stripTracking && c.isTracking(key) {
+			continue
+		}
+
+		params = append(params, queryParam{key: key, value: value, hasValue: hasValue})
+	}
+
+	sort.SliceStable(params, func(i, j int) bool {
+		return params[i].key < params[j].key
+	})
+
+	var b strings.Builder
+	for i, param := range params {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+
+		b.WriteString(netURL.QueryEscape(param.key))
+		if param.hasValue {
+			b.WriteByte('=')
+			b.WriteString(netURL.QueryEscape(param.value))
+		}
+	}
+
+	return b.String()
+}
+
+func (c *Canonicalizer) isTracking(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasPrefix(key, "utm_") {
+		return true
+	}
+
+	_, ok := c.trackingParams[key]
+	return ok
+}
+
+// removeDotSegments implements RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+
+	return strings.Join(out, "/")
+}
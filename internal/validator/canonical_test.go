@@ -0,0 +1,154 @@
+package validator_test
+
+import (
+	"testing"
+
+	"shortener/internal/validator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		options   validator.CanonicalizerOptions
+		url       string
+		wantURL   string
+		wantValid bool
+	}{
+		{
+			name:      "scheme host and default port",
+			url:       "HTTP://Example.COM:80/a?b=1&a=2",
+			wantURL:   "http://example.com/a?a=2&b=1",
+			wantValid: true,
+		},
+		{
+			name:      "https default port",
+			url:       "https://example.com:443",
+			wantURL:   "https://example.com",
+			wantValid: true,
+		},
+		{
+			name:      "custom port kept",
+			url:       "https://example.com:8443/",
+			wantURL:   "https://example.com:8443",
+			wantValid: true,
+		},
+		{
+			name:      "dot segments",
+			url:       "http://example.com/a/./b/../c/",
+			wantURL:   "http://example.com/a/c",
+			wantValid: true,
+		},
+		{
+			name:      "dot segments above root",
+			url:       "http://example.com/../../a",
+			wantURL:   "http://example.com/a",
+			wantValid: true,
+		},
+		{
+			name:      "escaped path kept",
+			url:       "http://example.com/a%2Fb",
+			wantURL:   "http://example.com/a%2Fb",
+			wantValid: true,
+		},
+		{
+			name:      "tracking params",
+			options:   validator.CanonicalizerOptions{StripTracking: true},
+			url:       "http://example.com/?utm_source=x&UTM_Medium=y&fbclid=z&id=1",
+			wantURL:   "http://example.com?id=1",
+			wantValid: true,
+		},
+		{
+			name:      "only tracking params",
+			options:   validator.CanonicalizerOptions{StripTracking: true},
+			url:       "http://example.com/a?utm_source=x",
+			wantURL:   "http://example.com/a",
+			wantValid: true,
+		},
+		{
+			name:      "custom tracking params",
+			options:   validator.CanonicalizerOptions{StripTracking: true, TrackingParams: []string{"gclid"}},
+			url:       "http://example.com/?gclid=1&q=go",
+			wantURL:   "http://example.com?q=go",
+			wantValid: true,
+		},
+		{
+			name:      "tracking params kept",
+			url:       "http://example.com/?utm_source=x",
+			wantURL:   "http://example.com?utm_source=x",
+			wantValid: true,
+		},
+		{
+			name:      "valueless keys kept",
+			url:       "http://example.com/a?flag&b=&a=1",
+			wantURL:   "http://example.com/a?a=1&b=&flag",
+			wantValid: true,
+		},
+		{
+			name:      "repeated keys keep order",
+			url:       "http://example.com/a?b=2&a=1&b=1",
+			wantURL:   "http://example.com/a?a=1&b=2&b=1",
+			wantValid: true,
+		},
+		{
+			name:      "escaping normalized",
+			url:       "http://example.com/a?q=a+b&r=%7e",
+			wantURL:   "http://example.com/a?q=a+b&r=~",
+			wantValid: true,
+		},
+		{
+			name:      "semicolon query kept",
+			url:       "http://example.com/a?b=1;a=2",
+			wantURL:   "http://example.com/a?b=1;a=2",
+			wantValid: true,
+		},
+		{
+			name:      "fragment kept",
+			url:       "http://example.com/a#top",
+			wantURL:   "http://example.com/a#top",
+			wantValid: true,
+		},
+		{
+			name:      "fragment dropped",
+			options:   validator.CanonicalizerOptions{DropFragment: true},
+			url:       "http://example.com/a#top",
+			wantURL:   "http://example.com/a",
+			wantValid: true,
+		},
+		{
+			name:      "idn host",
+			url:       "https://Пример.рф/путь",
+			wantURL:   "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C",
+			wantValid: true,
+		},
+		{
+			name:      "ipv6 host",
+			url:       "http://[::1]:80/",
+			wantURL:   "http://[::1]",
+			wantValid: true,
+		},
+		{
+			name:      "no host",
+			url:       "http:///path",
+			wantValid: false,
+		},
+		{
+			name:      "invalid idn host",
+			url:       "http://exa_mple..com",
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, _ := validator.NewValidator("abc", 10)
+			v.SetCanonicalizer(validator.NewCanonicalizer(tt.options))
+
+			gotURL, gotValid := v.ValidateURL(tt.url)
+
+			assert.Equal(t, tt.wantValid, gotValid)
+			assert.Equal(t, tt.wantURL, gotURL)
+		})
+	}
+}
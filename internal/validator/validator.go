@@ -6,9 +6,28 @@ import (
 	"strings"
 )
 
+const (
+	aliasMinLen = 3
+	aliasMaxLen = 32
+)
+
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"health":  {},
+	"metrics": {},
+	"admin":   {},
+	"static":  {},
+	"assets":  {},
+	"favicon": {},
+	"robots":  {},
+	"login":   {},
+	"logout":  {},
+}
+
 type Validator struct {
-	letters map[rune]struct{}
-	len     int
+	letters       map[rune]struct{}
+	len           int
+	canonicalizer *Canonicalizer
 }
 
 func NewValidator(alphabet string, size int) (*Validator, error) {
@@ -23,28 +42,28 @@ func NewValidator(alphabet string, size int) (*Validator, error) {
 	}
 
 	return &Validator{
-		letters: m,
-		len:     size,
+		letters:       m,
+		len:           size,
+		canonicalizer: NewCanonicalizer(CanonicalizerOptions{StripTracking: true}),
 	}, nil
 }
 
+func (v *Validator) SetCanonicalizer(canonicalizer *Canonicalizer) {
+	v.canonicalizer = canonicalizer
+}
+
 func (v *Validator) ValidateURL(url string) (string, bool) {
 	url = strings.TrimSpace(url)
 	if url == "" {
 		return "", false
 	}
 
-	parsed, err := netURL.ParseRequestURI(url)
+	parsed, err := netURL.Parse(url)
 	if err != nil {
 		return "", false
 	}
 
-	if parsed.Scheme != "http" && parsed.Scheme != "https" {
-		return "", false
-	}
-
-	normalized := strings.TrimRight(parsed.String(), "/")
-	return normalized, true
+	return v.canonicalizer.Canonicalize(parsed)
 }
 
 func (v *Validator) ValidateShortened(shortened string) bool {
@@ -60,3 +79,32 @@ func (v *Validator) ValidateShortened(shortened string) bool {
 
 	return true
 }
+
+func (v *Validator) ValidateAlias(alias string) bool {
+	if len(alias) < aliasMinLen || len(alias) > aliasMaxLen {
+		return false
+	}
+
+	if alias[0] == '-' || alias[len(alias)-1] == '-' {
+		return false
+	}
+
+	for _, r := range alias {
+		if !isAliasRune(r) {
+			return false
+		}
+	}
+
+	return !v.IsReserved(alias)
+}
+
+// IsReserved reports whether code would shadow one of the service's own
+// routes, so it may be neither an alias nor a generated code.
+func (v *Validator) IsReserved(code string) bool {
+	_, ok := reservedAliases[strings.ToLower(code)]
+	return ok
+}
+
+func isAliasRune(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_'
+}
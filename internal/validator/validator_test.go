@@ -113,3 +113,81 @@ func TestValidateShortened(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateAlias(t *testing.T) {
+	v, _ := validator.NewValidator("abc", 10)
+
+	tests := []struct {
+		name      string
+		alias     string
+		wantValid bool
+	}{
+		{
+			name:      "ok",
+			alias:     "spring-sale",
+			wantValid: true,
+		},
+		{
+			name:      "underscore",
+			alias:     "Spring_Sale_2024",
+			wantValid: true,
+		},
+		{
+			name:      "short",
+			alias:     "ab",
+			wantValid: false,
+		},
+		{
+			name:      "long",
+			alias:     "abcdefghijklmnopqrstuvwxyz0123456",
+			wantValid: false,
+		},
+		{
+			name:      "leading dash",
+			alias:     "-sale",
+			wantValid: false,
+		},
+		{
+			name:      "trailing dash",
+			alias:     "sale-",
+			wantValid: false,
+		},
+		{
+			name:      "invalid character",
+			alias:     "sale!",
+			wantValid: false,
+		},
+		{
+			name:      "non ascii",
+			alias:     "распродажа",
+			wantValid: false,
+		},
+		{
+			name:      "reserved",
+			alias:     "health",
+			wantValid: false,
+		},
+		{
+			name:      "reserved upper",
+			alias:     "API",
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotValid := v.ValidateAlias(tt.alias)
+			assert.Equal(t, tt.wantValid, gotValid)
+		})
+	}
+}
+
+func TestIsReserved(t *testing.T) {
+	v, _ := validator.NewValidator("aip", 3)
+
+	// the generator can produce "api" from this alphabet
+	assert.True(t, v.ValidateShortened("api"))
+	assert.True(t, v.IsReserved("api"))
+	assert.True(t, v.IsReserved("Metrics"))
+	assert.False(t, v.IsReserved("aaa"))
+}
@@ -6,6 +6,7 @@ import (
 
 type Logger interface {
 	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
 	Error(msg string, fields ...Field)
 	Debug(msg string, fields ...Field)
 	With(fields ...Field) Logger
@@ -39,10 +40,21 @@ func New(lvl string) (Logger, error) {
 	}, nil
 }
 
+// NewNop returns a logger that discards everything, for tests.
+func NewNop() Logger {
+	return &zapLogger{
+		log: zap.NewNop(),
+	}
+}
+
 func (z *zapLogger) Info(msg string, fields ...Field) {
 	z.log.Info(msg, toZap(fields)...)
 }
 
+func (z *zapLogger) Warn(msg string, fields ...Field) {
+	z.log.Warn(msg, toZap(fields)...)
+}
+
 func (z *zapLogger) Error(msg string, fields ...Field) {
 	z.log.Error(msg, toZap(fields)...)
 }